@@ -0,0 +1,410 @@
+package enls
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────
+// Archives (archive column, release.zip/ and --archive)
+// ─────────────────────────────────────────────
+//
+// zip, tar, tar.gz and tar.bz2 are read with the standard library, tar.xz
+// with the decoder in xz.go. zstd compressed tarballs are recognised but
+// not decoded.
+
+var errNotArchive = errors.New("not a supported archive")
+
+// unsupportedArchiveError reports a tar archive whose compression enls
+// recognises but cannot read; the standard library has no zstd decoder.
+type unsupportedArchiveError struct {
+	path, kind string
+}
+
+func (e *unsupportedArchiveError) Error() string {
+	return fmt.Sprintf("%s: %s-compressed archives are not supported", e.path, e.kind)
+}
+
+// archiveEntry is one member of an archive.
+type archiveEntry struct {
+	info  fs.FileInfo
+	owner string
+	group string
+	link  string
+}
+
+// archiveIndex holds the members of an archive by slash-separated path.
+// The root directory is "".
+type archiveIndex struct {
+	path     string
+	root     fs.FileInfo
+	entries  map[string]*archiveEntry
+	children map[string][]string
+}
+
+// archiveDirInfo stands in for directories that an archive implies but
+// does not store, such as the parents of "a/b/c.txt" in most zip files.
+type archiveDirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (d archiveDirInfo) Name() string       { return d.name }
+func (d archiveDirInfo) Size() int64        { return 0 }
+func (d archiveDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0755 }
+func (d archiveDirInfo) ModTime() time.Time { return d.modTime }
+func (d archiveDirInfo) IsDir() bool        { return true }
+func (d archiveDirInfo) Sys() any           { return nil }
+
+// openArchive is the archive being listed or rendered, if any. Lister.list
+// sets it from the path it resolves and records it in the entries it
+// returns; Lister.render installs it again from them.
+var openArchive *archiveIndex
+
+// archiveKind identifies a supported archive from its leading bytes,
+// looking through gzip, bzip2 and xz compression for a tar header.
+func archiveKind(p string) (string, error) {
+	f, err := openPath(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	kind, _, _, ok := detectMagic(head)
+	if !ok {
+		return "", errNotArchive
+	}
+
+	var inner io.Reader
+	switch kind {
+	case "zip", "tar":
+		return kind, nil
+	case "gzip":
+		zr, err := gzip.NewReader(io.MultiReader(bytes.NewReader(head), f))
+		if err != nil {
+			return "", errNotArchive
+		}
+		inner = zr
+	case "bzip2":
+		inner = bzip2.NewReader(io.MultiReader(bytes.NewReader(head), f))
+	case "xz":
+		zr, err := newXZReader(io.MultiReader(bytes.NewReader(head), f))
+		if err != nil {
+			return "", errNotArchive
+		}
+		inner = zr
+	case "zstd":
+		if isTarName(p) {
+			return "", &unsupportedArchiveError{p, kind}
+		}
+		return "", errNotArchive
+	default:
+		return "", errNotArchive
+	}
+
+	block := make([]byte, 512)
+	if _, err := io.ReadFull(inner, block); err != nil || !bytes.Equal(block[257:262], []byte("ustar")) {
+		return "", errNotArchive
+	}
+	return "tar+" + kind, nil
+}
+
+func isTarName(p string) bool {
+	name := strings.ToLower(baseName(p))
+	for _, ext := range []string{".tar.zst", ".tzst"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// tarReader reads f as a possibly compressed tar stream.
+func tarReader(f io.Reader, kind string) (*tar.Reader, error) {
+	br := bufio.NewReader(f)
+	switch kind {
+	case "tar+gzip":
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(zr), nil
+	case "tar+bzip2":
+		return tar.NewReader(bzip2.NewReader(br)), nil
+	case "tar+xz":
+		zr, err := newXZReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(zr), nil
+	}
+	return tar.NewReader(br), nil
+}
+
+// walkArchive calls fn for every member of the archive at p.
+func walkArchive(p string, fn func(name string, e *archiveEntry)) error {
+	kind, err := archiveKind(p)
+	if err != nil {
+		return err
+	}
+
+	f, err := openPath(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if kind == "zip" {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		ra, ok := f.(io.ReaderAt)
+		if !ok {
+			return fmt.Errorf("%s: zip archives need random access", p)
+		}
+		zr, err := zip.NewReader(ra, info.Size())
+		if err != nil {
+			return err
+		}
+		for _, zf := range zr.File {
+			fn(zf.Name, &archiveEntry{info: zf.FileInfo()})
+		}
+		return nil
+	}
+
+	tr, err := tarReader(f, kind)
+	if err != nil {
+		return err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeXGlobalHeader, tar.TypeGNULongName, tar.TypeGNULongLink:
+			continue
+		}
+		e := &archiveEntry{info: hdr.FileInfo(), owner: hdr.Uname, group: hdr.Gname}
+		if e.owner == "" {
+			e.owner = strconv.Itoa(hdr.Uid)
+		}
+		if e.group == "" {
+			e.group = strconv.Itoa(hdr.Gid)
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			e.link = hdr.Linkname
+		}
+		fn(hdr.Name, e)
+	}
+}
+
+// cleanArchiveName normalises a member name: "./a/b/" becomes "a/b".
+func cleanArchiveName(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimPrefix(name, "/")
+}
+
+// loadArchive indexes the archive at p.
+func loadArchive(p string, archInfo fs.FileInfo) (*archiveIndex, error) {
+	a := &archiveIndex{
+		path:     p,
+		root:     archiveDirInfo{name: archInfo.Name(), modTime: archInfo.ModTime()},
+		entries:  map[string]*archiveEntry{},
+		children: map[string][]string{},
+	}
+	var add func(name string, e *archiveEntry)
+	add = func(name string, e *archiveEntry) {
+		if name == "" {
+			return
+		}
+		if old, ok := a.entries[name]; ok {
+			// A later member with the same name replaces the earlier one,
+			// as it would on extraction; implied directories get real info.
+			if !(e.info.IsDir() && !old.info.IsDir()) {
+				a.entries[name] = e
+			}
+			return
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		if _, ok := a.entries[dir]; !ok && dir != "" {
+			add(dir, &archiveEntry{info: archiveDirInfo{name: path.Base(dir), modTime: archInfo.ModTime()}})
+		}
+		a.entries[name] = e
+		a.children[dir] = append(a.children[dir], name)
+	}
+
+	err := walkArchive(p, func(name string, e *archiveEntry) {
+		add(cleanArchiveName(name), e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range a.children {
+		sort.Strings(c)
+	}
+	return a, nil
+}
+
+// openArchivePath recognises "release.zip/docs" and, with force (--archive
+// or a trailing slash), a plain archive path. On success it returns the
+// info of the listed member and the archive index.
+// errNotArchive means p is not inside an archive.
+func openArchivePath(p string, force bool) (fs.FileInfo, *archiveIndex, error) {
+	archPath, inner := p, ""
+	info, err := statPath(p)
+	if err == nil {
+		if !force || !info.Mode().IsRegular() {
+			return nil, nil, errNotArchive
+		}
+	} else {
+		// Look for an archive among the parents: release.zip/docs/a.md.
+		for {
+			parent := parentDir(archPath)
+			if parent == archPath {
+				return nil, nil, errNotArchive
+			}
+			inner = path.Join(baseName(archPath), inner)
+			archPath = parent
+			if info, err = statPath(archPath); err == nil {
+				break
+			}
+		}
+		if !info.Mode().IsRegular() {
+			return nil, nil, errNotArchive
+		}
+	}
+
+	a, err := loadArchive(archPath, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inner == "" {
+		return a.root, a, nil
+	}
+	e, ok := a.entries[inner]
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: no such entry in %s", inner, archPath)
+	}
+	return e.info, a, nil
+}
+
+// archiveFor reports whether p lies inside the open archive and returns
+// the member name.
+func archiveFor(p string) (*archiveIndex, string, bool) {
+	a := openArchive
+	if a == nil {
+		return nil, "", false
+	}
+	if p == a.path {
+		return a, "", true
+	}
+	rel, ok := strings.CutPrefix(p, a.path+pathSeparator())
+	if !ok {
+		return nil, "", false
+	}
+	if onDisk() {
+		rel = filepath.ToSlash(rel)
+	}
+	return a, rel, true
+}
+
+// readDir lists the members of an archive directory like a directory on
+// disk; Path is the archive path joined with the member name.
+func (a *archiveIndex) readDir(dir string, args *Options) []Entry {
+	var items []Entry
+	for _, name := range a.children[dir] {
+		e := a.entries[name]
+		if isIgnored(path.Base(name), args) {
+			continue
+		}
+		items = append(items, Entry{
+			FileInfo:  e.info,
+			Path:      joinPath(a.path, filepath.FromSlash(name)),
+			Links:     1,
+			OwnerName: e.owner,
+			GroupName: e.group,
+		})
+	}
+	return items
+}
+
+// readDirEntries lists the visible entries of a directory in fsys or inside
+// the open archive. Owners are left to the caller for entries in fsys.
+func readDirEntries(dir string, args *Options) ([]Entry, error) {
+	if a, inner, ok := archiveFor(dir); ok {
+		return a.readDir(inner, args), nil
+	}
+
+	entries, err := readDirPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return entryItems(dir, entries, args), nil
+}
+
+// readLink returns a symbolic link's target, in fsys or in the archive.
+func readLink(p string) (string, error) {
+	if a, inner, ok := archiveFor(p); ok {
+		if e := a.entries[inner]; e != nil && e.link != "" {
+			return e.link, nil
+		}
+		return "", fs.ErrNotExist
+	}
+	return readLinkPath(p)
+}
+
+// archiveSummary renders the archive column: member count and total
+// uncompressed size, e.g. "12 files, 3.4M". Archives that cannot be read
+// show "unsupported (zstd)" or "error" rather than an empty cell.
+func archiveSummary(p string) string {
+	if _, _, ok := archiveFor(p); ok {
+		return ""
+	}
+	var files int
+	var total int64
+	err := walkArchive(p, func(_ string, e *archiveEntry) {
+		if e.info.Mode().IsRegular() {
+			files++
+			total += e.info.Size()
+		}
+	})
+	var unsupported *unsupportedArchiveError
+	switch {
+	case err == errNotArchive:
+		return ""
+	case errors.As(err, &unsupported):
+		return "unsupported (" + unsupported.kind + ")"
+	case err != nil:
+		return "error"
+	}
+	noun := "files"
+	if files == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("%d %s, %s", files, noun, formatSize(total))
+}
@@ -0,0 +1,153 @@
+package enls
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// tarFile builds a tar archive; names ending in "/" are directories and
+// "name->target" is a symbolic link.
+func tarFile(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, Uname: "alice", Gname: "staff", Typeflag: tar.TypeReg}
+		if n, target, ok := strings.Cut(name, "->"); ok {
+			hdr.Name, hdr.Linkname, hdr.Typeflag = n, target, tar.TypeSymlink
+		} else if strings.HasSuffix(name, "/") {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		} else {
+			hdr.Size = int64(len(name))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write([]byte(name))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestLoadArchive(t *testing.T) {
+	txz, err := os.ReadFile(filepath.Join("testdata", "release.tar.xz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := []string{"./docs/", "./docs/a.md", "./docs/b.md", "./bin/tool", "./latest->bin/tool"}
+	fsys := fstest.MapFS{
+		// The repeated docs/a.md replaces the first in the index but still
+		// counts towards the summary, like the bytes it takes up.
+		"a.zip":          {Data: zipFile(t, "docs/b.md", "docs/a.md", "bin/tool", "docs/a.md")},
+		"a.tar":          {Data: tarFile(t, members...)},
+		"a.tar.gz":       {Data: gzipData(t, tarFile(t, members...))},
+		"release.tar.xz": {Data: txz},
+		"plain.gz":       {Data: gzipData(t, []byte("not a tarball"))},
+		"notes.txt":      {Data: []byte("hello")},
+		"b.tar.zst":      {Data: []byte{0x28, 0xb5, 0x2f, 0xfd, 0, 0, 0, 0}},
+	}
+	useFS(fsys)
+	t.Cleanup(func() { useFS(nil) })
+
+	tests := []struct {
+		path       string
+		root       []string // children of the archive root
+		docs       []string // children of docs
+		link       string   // target of "latest", if any
+		owner      string   // owner of bin/tool
+		summary    string
+		notArchive bool
+	}{
+		{path: "a.zip", root: []string{"bin", "docs"}, docs: []string{"docs/a.md", "docs/b.md"}, summary: "4 files, 83"},
+		{path: "a.tar", root: []string{"bin", "docs", "latest"}, docs: []string{"docs/a.md", "docs/b.md"}, link: "bin/tool", owner: "alice", summary: "3 files, 32"},
+		{path: "a.tar.gz", root: []string{"bin", "docs", "latest"}, docs: []string{"docs/a.md", "docs/b.md"}, link: "bin/tool", owner: "alice", summary: "3 files, 32"},
+		{path: "release.tar.xz", root: []string{"bin", "docs"}, docs: []string{"docs/readme.txt"}, summary: "2 files, 70"},
+		{path: "plain.gz", notArchive: true},
+		{path: "notes.txt", notArchive: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			info, err := statPath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := loadArchive(tt.path, info)
+			if tt.notArchive {
+				if err != errNotArchive {
+					t.Errorf("error %v, want errNotArchive", err)
+				}
+				if s := archiveSummary(tt.path); s != "" {
+					t.Errorf("summary %q, want none", s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.children[""]; !slices.Equal(got, tt.root) {
+				t.Errorf("root %q, want %q", got, tt.root)
+			}
+			if got := a.children["docs"]; !slices.Equal(got, tt.docs) {
+				t.Errorf("docs %q, want %q", got, tt.docs)
+			}
+			if d := a.entries["docs"]; d == nil || !d.info.IsDir() {
+				t.Error("docs is not a directory")
+			}
+			if tt.link != "" {
+				if e := a.entries["latest"]; e == nil || e.link != tt.link {
+					t.Errorf("latest: %+v, want a link to %s", e, tt.link)
+				}
+			}
+			if tt.owner != "" {
+				if e := a.entries["bin/tool"]; e == nil || e.owner != tt.owner {
+					t.Errorf("bin/tool: %+v, want owner %s", e, tt.owner)
+				}
+			}
+			if s := archiveSummary(tt.path); s != tt.summary {
+				t.Errorf("summary %q, want %q", s, tt.summary)
+			}
+		})
+	}
+
+	if s := archiveSummary("b.tar.zst"); s != "unsupported (zstd)" {
+		t.Errorf("zstd summary %q", s)
+	}
+}
+
+func TestCleanArchiveName(t *testing.T) {
+	tests := map[string]string{
+		"a/b/":        "a/b",
+		"./a/b.txt":   "a/b.txt",
+		`dir\file`:    "dir/file",
+		"../../etc/x": "etc/x",
+		"/abs/path":   "abs/path",
+		"./":          "",
+	}
+	for in, want := range tests {
+		if got := cleanArchiveName(in); got != want {
+			t.Errorf("cleanArchiveName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
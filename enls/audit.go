@@ -0,0 +1,177 @@
+package enls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// ─────────────────────────────────────────────
+// Security audit (--audit)
+// ─────────────────────────────────────────────
+
+type auditSeverity int
+
+const (
+	auditLow auditSeverity = iota
+	auditMedium
+	auditHigh
+)
+
+var auditSeverityNames = [...]string{"LOW", "MEDIUM", "HIGH"}
+
+var auditSeverityColors = [...]string{
+	"\033[96m", // cyan
+	"\033[93m", // yellow
+	"\033[91m", // bright red
+}
+
+type auditFinding struct {
+	severity auditSeverity
+	path     string
+	reason   string
+}
+
+// auditEntry checks one entry. Permission checks are skipped where the
+// mode bits are synthesised (Windows).
+func auditEntry(info fs.FileInfo, path string) []auditFinding {
+	var findings []auditFinding
+	add := func(sev auditSeverity, reason string) {
+		findings = append(findings, auditFinding{sev, path, reason})
+	}
+
+	mode := info.Mode()
+	if mode&fs.ModeSymlink != 0 {
+		if _, err := statPath(path); err != nil {
+			add(auditLow, "dangling symlink")
+		}
+		return findings
+	}
+	if !hasExecBit {
+		return findings
+	}
+
+	perm := mode.Perm()
+	switch {
+	case mode.IsDir() && perm&0002 != 0 && mode&fs.ModeSticky == 0:
+		add(auditHigh, "world-writable directory without sticky bit")
+	case mode.IsRegular() && perm&0002 != 0:
+		add(auditHigh, "world-writable file")
+	case mode.IsRegular() && perm&0111 != 0 && perm&0020 != 0:
+		add(auditMedium, "group-writable executable")
+	}
+	if mode.IsRegular() && mode&fs.ModeSetuid != 0 {
+		add(auditHigh, "setuid")
+	}
+	if mode.IsRegular() && mode&fs.ModeSetgid != 0 {
+		add(auditMedium, "setgid")
+	}
+
+	// Lookups fall back to the numeric id when the user or group has no
+	// passwd/group entry, even with -n.
+	owner, group := lookupOwnerGroup(info, true)
+	if isNumericID(owner) {
+		add(auditMedium, "owned by unknown uid "+owner)
+	}
+	if isNumericID(group) {
+		add(auditMedium, "owned by unknown gid "+group)
+	}
+	return findings
+}
+
+func isNumericID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// runAudit walks root, prints the findings and a severity summary, and
+// returns the exit status: 1 when anything was flagged. Hidden entries are
+// always audited; --ignore patterns are honoured.
+func runAudit(ctx context.Context, w, errOut io.Writer, root string, args *Options) int {
+	var findings []auditFinding
+	scanned := 0
+
+	withHidden := *args
+	withHidden.ShowAll = true
+
+	var scan *scanState
+	var walk func(dir string)
+	walk = func(dir string) {
+		if ctx.Err() != nil || !scan.enterDir(dir) {
+			return
+		}
+		entries, err := readDirPath(dir)
+		if err != nil {
+			problems.add("cannot read directory", dir, err)
+			return
+		}
+		for _, entry := range entries {
+			if isIgnored(entry.Name(), &withHidden) {
+				continue
+			}
+			if ctx.Err() != nil || !scan.take() {
+				return
+			}
+			fullPath := joinPath(dir, entry.Name())
+			info, err := lstatPath(fullPath)
+			if err != nil {
+				problems.add("cannot access", fullPath, err)
+				continue
+			}
+			scanned++
+			findings = append(findings, auditEntry(info, fullPath)...)
+			if info.IsDir() {
+				walk(fullPath)
+			}
+		}
+	}
+
+	info, err := lstatPath(root)
+	if err != nil {
+		fmt.Fprintf(errOut, "Error accessing path: %v\n", err)
+		return ExitSerious
+	}
+	scanned++
+	findings = append(findings, auditEntry(info, root)...)
+	truncated := false
+	if info.IsDir() {
+		scan = startScan(args, w, errOut)
+		walk(root)
+		truncated = scan.finish()
+	}
+
+	var counts [len(auditSeverityNames)]int
+	labelWidth, reasonWidth := 0, 0
+	for _, name := range auditSeverityNames {
+		labelWidth = maxInt(labelWidth, len(name))
+	}
+	for _, f := range findings {
+		reasonWidth = maxInt(reasonWidth, len(f.reason))
+	}
+	for _, f := range findings {
+		counts[f.severity]++
+		label := padByWidth(auditSeverityNames[f.severity], labelWidth)
+		if colorEnabled {
+			label = auditSeverityColors[f.severity] + label + ansiReset
+		}
+		fmt.Fprintf(w, "%s  %s  %s\n", label, padByWidth(f.reason, reasonWidth), f.path)
+	}
+
+	if len(findings) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d high, %d medium, %d low (%d entries scanned)\n",
+		counts[auditHigh], counts[auditMedium], counts[auditLow], scanned)
+	if len(findings) > 0 || truncated {
+		return ExitMinor
+	}
+	return ExitOK
+}
@@ -0,0 +1,300 @@
+package enls
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ─────────────────────────────────────────────
+// Executable headers (arch column, --arch filter)
+// ─────────────────────────────────────────────
+
+// BinaryInfo summarises an ELF, PE or Mach-O header.
+type BinaryInfo struct {
+	Format   string // elf, pe, macho
+	OS       string // linux, windows, darwin, ...
+	Arch     string // GOARCH-style: amd64, arm64, loong64, ...
+	Bits     int
+	Static   bool
+	Stripped bool
+}
+
+// String renders the info for the arch column, e.g.
+// "linux/amd64, 64-bit, static, not stripped".
+func (b *BinaryInfo) String() string {
+	parts := []string{b.OS + "/" + b.Arch}
+	if b.Bits != 0 {
+		parts = append(parts, strconv.Itoa(b.Bits)+"-bit")
+	}
+	if b.Static {
+		parts = append(parts, "static")
+	} else {
+		parts = append(parts, "dynamic")
+	}
+	if b.Stripped {
+		parts = append(parts, "stripped")
+	} else {
+		parts = append(parts, "not stripped")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Matches reports whether filter names this binary's OS, architecture or
+// "os/arch" pair. Universal Mach-O binaries match any of their slices.
+func (b *BinaryInfo) Matches(filter string) bool {
+	filter = strings.ToLower(filter)
+	if filter == b.OS {
+		return true
+	}
+	for _, arch := range strings.Split(b.Arch, "+") {
+		if filter == arch || filter == b.OS+"/"+arch {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	binaryMu    sync.Mutex
+	binaryCache = map[string]*BinaryInfo{}
+)
+
+var elfArchNames = map[elf.Machine]string{
+	elf.EM_X86_64:    "amd64",
+	elf.EM_386:       "386",
+	elf.EM_AARCH64:   "arm64",
+	elf.EM_ARM:       "arm",
+	elf.EM_LOONGARCH: "loong64",
+	elf.EM_RISCV:     "riscv",
+	elf.EM_PPC64:     "ppc64",
+	elf.EM_PPC:       "ppc",
+	elf.EM_S390:      "s390x",
+	elf.EM_MIPS:      "mips",
+}
+
+var peArchNames = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_AMD64:       "amd64",
+	pe.IMAGE_FILE_MACHINE_I386:        "386",
+	pe.IMAGE_FILE_MACHINE_ARM64:       "arm64",
+	pe.IMAGE_FILE_MACHINE_ARMNT:       "arm",
+	pe.IMAGE_FILE_MACHINE_LOONGARCH64: "loong64",
+	pe.IMAGE_FILE_MACHINE_RISCV64:     "riscv64",
+}
+
+var machoArchNames = map[macho.Cpu]string{
+	macho.CpuAmd64: "amd64",
+	macho.Cpu386:   "386",
+	macho.CpuArm64: "arm64",
+	macho.CpuArm:   "arm",
+	macho.CpuPpc64: "ppc64",
+	macho.CpuPpc:   "ppc",
+}
+
+// binaryInfo parses the executable header of a regular file. Files are
+// first checked against the sniffed magic so only real binaries are
+// opened by the debug/* parsers. It returns nil for anything else.
+func binaryInfo(info fs.FileInfo, path string) *BinaryInfo {
+	r := readHead(info, path)
+	if r == nil || !r.ok {
+		return nil
+	}
+	switch r.kind {
+	case "elf", "pe", "macho", "macho-fat":
+	default:
+		return nil
+	}
+
+	binaryMu.Lock()
+	if b, ok := binaryCache[path]; ok {
+		binaryMu.Unlock()
+		return b
+	}
+	binaryMu.Unlock()
+
+	var b *BinaryInfo
+	switch r.kind {
+	case "elf":
+		b = elfInfo(path)
+	case "pe":
+		b = peInfo(path)
+	default:
+		b = machoInfo(path)
+	}
+
+	binaryMu.Lock()
+	binaryCache[path] = b
+	binaryMu.Unlock()
+	return b
+}
+
+// maxBufferedBinary bounds how much of a file without random access is
+// read into memory for the debug/* parsers.
+const maxBufferedBinary = 64 << 20
+
+// openReaderAt opens path in fsys for random access. Files that are not an
+// io.ReaderAt, such as zip members, are buffered up to maxBufferedBinary.
+func openReaderAt(path string) (io.ReaderAt, io.Closer, error) {
+	f, err := openPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ra, ok := f.(io.ReaderAt); ok {
+		return ra, f, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxBufferedBinary))
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return bytes.NewReader(data), f, nil
+}
+
+func elfInfo(path string) *BinaryInfo {
+	ra, c, err := openReaderAt(path)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+	f, err := elf.NewFile(ra)
+	if err != nil {
+		return nil
+	}
+
+	b := &BinaryInfo{Format: "elf", Bits: 32, Static: true, Stripped: true}
+	if f.Class == elf.ELFCLASS64 {
+		b.Bits = 64
+	}
+
+	switch f.OSABI {
+	case elf.ELFOSABI_NONE, elf.ELFOSABI_LINUX:
+		b.OS = "linux"
+	default:
+		b.OS = strings.ToLower(strings.TrimPrefix(f.OSABI.String(), "ELFOSABI_"))
+	}
+
+	arch, ok := elfArchNames[f.Machine]
+	if !ok {
+		arch = strings.ToLower(strings.TrimPrefix(f.Machine.String(), "EM_"))
+	}
+	switch {
+	case arch == "riscv" || arch == "mips":
+		if b.Bits == 64 {
+			arch += "64"
+		}
+	case arch == "ppc64" && f.ByteOrder.String() == "LittleEndian":
+		arch = "ppc64le"
+	}
+	b.Arch = arch
+
+	// static-pie executables have PT_DYNAMIC for their own relocations
+	// but neither an interpreter nor DT_NEEDED libraries.
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_INTERP {
+			b.Static = false
+		}
+	}
+	if libs, _ := f.ImportedLibraries(); len(libs) > 0 {
+		b.Static = false
+	}
+	if f.Section(".symtab") != nil {
+		b.Stripped = false
+	}
+	return b
+}
+
+func peInfo(path string) *BinaryInfo {
+	ra, c, err := openReaderAt(path)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+	f, err := pe.NewFile(ra)
+	if err != nil {
+		return nil
+	}
+
+	b := &BinaryInfo{Format: "pe", OS: "windows", Bits: 32}
+	if _, ok := f.OptionalHeader.(*pe.OptionalHeader64); ok {
+		b.Bits = 64
+	}
+	arch, ok := peArchNames[f.Machine]
+	if !ok {
+		arch = "unknown"
+	}
+	b.Arch = arch
+
+	// debug/pe does not implement ImportedLibraries; imported symbols
+	// ("Func:dll") tell the same story.
+	syms, _ := f.ImportedSymbols()
+	b.Static = len(syms) == 0
+	b.Stripped = f.NumberOfSymbols == 0 && f.Section(".symtab") == nil
+	return b
+}
+
+func machoInfo(path string) *BinaryInfo {
+	ra, c, err := openReaderAt(path)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+
+	if fat, err := macho.NewFatFile(ra); err == nil {
+		var arches []string
+		var first *BinaryInfo
+		for _, a := range fat.Arches {
+			info := machoFileInfo(a.File)
+			if first == nil {
+				first = info
+			}
+			arches = append(arches, info.Arch)
+		}
+		if first == nil {
+			return nil
+		}
+		first.Arch = strings.Join(arches, "+")
+		first.Bits = 0
+		return first
+	}
+
+	f, err := macho.NewFile(ra)
+	if err != nil {
+		return nil
+	}
+	return machoFileInfo(f)
+}
+
+func machoFileInfo(f *macho.File) *BinaryInfo {
+	b := &BinaryInfo{Format: "macho", OS: "darwin", Bits: 32}
+	if f.Magic == macho.Magic64 {
+		b.Bits = 64
+	}
+	arch, ok := machoArchNames[f.Cpu]
+	if !ok {
+		arch = strings.ToLower(f.Cpu.String())
+	}
+	b.Arch = arch
+
+	libs, _ := f.ImportedLibraries()
+	b.Static = len(libs) == 0
+
+	// strip(1) removes local and debugging symbols; exported and imported
+	// ones always remain.
+	b.Stripped = true
+	if f.Symtab != nil {
+		for _, sym := range f.Symtab.Syms {
+			const nStab, nExt, nType, nSect = 0xe0, 0x01, 0x0e, 0x0e
+			if sym.Type&nStab != 0 || (sym.Type&nExt == 0 && sym.Type&nType == nSect) {
+				b.Stripped = false
+				break
+			}
+		}
+	}
+	return b
+}
@@ -0,0 +1,48 @@
+package enls
+
+import "testing"
+
+func TestBinaryInfoMatches(t *testing.T) {
+	linux := &BinaryInfo{Format: "elf", OS: "linux", Arch: "amd64", Bits: 64}
+	universal := &BinaryInfo{Format: "macho", OS: "darwin", Arch: "amd64+arm64"}
+	tests := []struct {
+		b      *BinaryInfo
+		filter string
+		want   bool
+	}{
+		{linux, "linux", true},
+		{linux, "amd64", true},
+		{linux, "linux/amd64", true},
+		{linux, "Linux/AMD64", true},
+		{linux, "arm64", false},
+		{linux, "linux/arm64", false},
+		{linux, "windows/amd64", false},
+		{linux, "amd", false},
+		{universal, "darwin", true},
+		{universal, "arm64", true},
+		{universal, "darwin/amd64", true},
+		{universal, "amd64+arm64", false},
+		{universal, "linux/arm64", false},
+	}
+	for _, tt := range tests {
+		if got := tt.b.Matches(tt.filter); got != tt.want {
+			t.Errorf("%s/%s Matches(%q) = %v, want %v", tt.b.OS, tt.b.Arch, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestBinaryInfoString(t *testing.T) {
+	tests := []struct {
+		b    BinaryInfo
+		want string
+	}{
+		{BinaryInfo{OS: "linux", Arch: "amd64", Bits: 64, Static: true}, "linux/amd64, 64-bit, static, not stripped"},
+		{BinaryInfo{OS: "windows", Arch: "386", Bits: 32, Stripped: true}, "windows/386, 32-bit, dynamic, stripped"},
+		{BinaryInfo{OS: "darwin", Arch: "amd64+arm64"}, "darwin/amd64+arm64, dynamic, not stripped"},
+	}
+	for _, tt := range tests {
+		if got := tt.b.String(); got != tt.want {
+			t.Errorf("%q, want %q", got, tt.want)
+		}
+	}
+}
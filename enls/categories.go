@@ -0,0 +1,165 @@
+package enls
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ─────────────────────────────────────────────
+// User-defined categories & classification rules
+// ─────────────────────────────────────────────
+//
+// The config file may declare extra categories, each with its own indicator
+// character and color, and classify entries into any category by exact
+// file name, glob or extension:
+//
+//	[categories.data]
+//	indicator = "&"
+//	color     = "#b58900"
+//
+//	[extensions]
+//	".sql" = "data"
+//	".log" = "other"
+//
+//	[filenames]
+//	"Makefile"   = "executable"
+//	"Dockerfile" = "executable"
+//
+//	[globs]
+//	"*~"    = "backup"
+//	"#*#"   = "backup"
+//	"*.swp" = "backup"
+//
+// Name rules win over globs, globs over the executable bit, and extension
+// rules over the built-in extension tables.
+
+// Category is a user-defined file type.
+type Category struct {
+	Name      string
+	Type      FileType
+	Indicator string
+	Style     Style
+}
+
+type globRule struct {
+	pattern string
+	ft      FileType
+}
+
+var (
+	categories     []*Category
+	categoryByName = map[string]*Category{}
+
+	nameRules    = map[string]FileType{}
+	globRules    []globRule
+	userExtTypes = map[string]FileType{}
+)
+
+// isCustomType reports whether ft belongs to a user-defined category.
+func isCustomType(ft FileType) bool {
+	return ft > FileTypeBackup
+}
+
+// registerCategory adds a category and returns its FileType.
+func registerCategory(name, indicator string, st Style) (*Category, error) {
+	name = strings.ToLower(name)
+	if _, ok := themeFileTypeKeys[name]; ok {
+		return nil, fmt.Errorf("category %q clashes with a built-in type", name)
+	}
+	if _, ok := categoryByName[name]; ok {
+		return nil, fmt.Errorf("category %q is defined twice", name)
+	}
+	if utf8.RuneCountInString(indicator) != 1 || indicator == " " || indicator == "-" {
+		return nil, fmt.Errorf("category %q: indicator must be a single character", name)
+	}
+	for ft, ind := range typeIndicators {
+		if ind == indicator {
+			return nil, fmt.Errorf("category %q: indicator %q is already used by %s", name, indicator, fileTypeName(ft))
+		}
+	}
+
+	c := &Category{
+		Name:      name,
+		Type:      FileTypeBackup + FileType(len(categories)+1),
+		Indicator: indicator,
+		Style:     st,
+	}
+	categories = append(categories, c)
+	categoryByName[name] = c
+	typeIndicators[c.Type] = indicator
+	configTypeNames[name] = c.Type
+	return c, nil
+}
+
+// applyCategoryColors renders category styles into colorMap. It runs after
+// the color depth is known and before any theme is applied.
+func applyCategoryColors() {
+	for _, c := range categories {
+		colorMap[c.Type] = c.Style.Seq()
+	}
+}
+
+// fileTypeName returns the config/theme name of ft.
+func fileTypeName(ft FileType) string {
+	for name, t := range themeFileTypeKeys {
+		if t == ft {
+			return name
+		}
+	}
+	for _, c := range categories {
+		if c.Type == ft {
+			return c.Name
+		}
+	}
+	return "other"
+}
+
+// lookupFileTypeName resolves a built-in type or category name.
+func lookupFileTypeName(name string) (FileType, bool) {
+	name = strings.ToLower(name)
+	if ft, ok := themeFileTypeKeys[name]; ok {
+		return ft, true
+	}
+	if c, ok := categoryByName[name]; ok {
+		return c.Type, true
+	}
+	return FileTypeOther, false
+}
+
+// isTypeIndicator reports whether s is the indicator of any file type,
+// built-in or user-defined, and so usable with -f.
+func isTypeIndicator(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, ind := range typeIndicators {
+		if ind == s {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyByName applies the [filenames] and [globs] rules.
+func classifyByName(name string) (FileType, bool) {
+	if ft, ok := nameRules[name]; ok {
+		return ft, true
+	}
+	for _, r := range globRules {
+		if ok, _ := filepath.Match(r.pattern, name); ok {
+			return r.ft, true
+		}
+	}
+	return FileTypeOther, false
+}
+
+// categoryHelp lists the configured categories for the help text.
+func categoryHelp(color, reset string) string {
+	var b strings.Builder
+	for _, c := range categories {
+		fmt.Fprintf(&b, "    %s%s%s         %s (config)\n", color, c.Indicator, reset, c.Name)
+	}
+	return b.String()
+}
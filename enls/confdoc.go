@@ -0,0 +1,392 @@
+package enls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// Minimal TOML / YAML reader
+// ─────────────────────────────────────────────
+//
+// Theme and configuration files only need tables of strings and string
+// arrays, so rather than pulling in a dependency we read a small, well
+// defined subset of both formats into a flat, ordered list of
+// "section.key" entries.
+//
+// TOML: [section] / [a.b] headers, key = "string" | 'literal' | bare |
+// [array, ...] (arrays may span lines), quoted keys and # comments.
+//
+// YAML: indentation-nested mappings, "key: value", "- item" lists,
+// [flow, lists], quoted scalars and # comments.
+
+type confEntry struct {
+	Key    string
+	Values []string
+	Line   int
+}
+
+type confDoc struct {
+	Entries []confEntry
+	index   map[string]int
+}
+
+func (d *confDoc) set(key string, values []string, line int) {
+	if d.index == nil {
+		d.index = make(map[string]int)
+	}
+	if i, ok := d.index[key]; ok {
+		d.Entries[i] = confEntry{Key: key, Values: values, Line: line}
+		return
+	}
+	d.index[key] = len(d.Entries)
+	d.Entries = append(d.Entries, confEntry{Key: key, Values: values, Line: line})
+}
+
+// Get returns a scalar value.
+func (d *confDoc) Get(key string) (string, bool) {
+	i, ok := d.index[key]
+	if !ok || len(d.Entries[i].Values) == 0 {
+		return "", false
+	}
+	return d.Entries[i].Values[0], true
+}
+
+// List returns an array value; a scalar is returned as a one-element list.
+func (d *confDoc) List(key string) ([]string, bool) {
+	i, ok := d.index[key]
+	if !ok {
+		return nil, false
+	}
+	return d.Entries[i].Values, true
+}
+
+// Section returns the entries directly below prefix in file order, with the
+// prefix stripped from their keys.
+func (d *confDoc) Section(prefix string) []confEntry {
+	var out []confEntry
+	for _, e := range d.Entries {
+		if rest, ok := strings.CutPrefix(e.Key, prefix+"."); ok {
+			e.Key = rest
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// readConfDoc loads path, choosing the syntax from its extension.
+func readConfDoc(path string) (*confDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc *confDoc
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		doc, err = parseYAMLDoc(string(data))
+	default:
+		doc, err = parseTOMLDoc(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// ── TOML ─────────────────────────────────────
+
+func parseTOMLDoc(text string) (*confDoc, error) {
+	doc := &confDoc{}
+	section := ""
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	for n := 0; n < len(lines); n++ {
+		lineNo := n + 1
+		line := strings.TrimSpace(stripComment(lines[n]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && !strings.HasPrefix(line, "[[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header", lineNo)
+			}
+			parts, err := splitDottedKey(strings.TrimSpace(line[1 : len(line)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			section = strings.Join(parts, ".")
+			continue
+		}
+
+		rawKey, rawValue, ok := cutUnquoted(line, '=')
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		keyParts, err := splitDottedKey(strings.TrimSpace(rawKey))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		rawValue = strings.TrimSpace(rawValue)
+
+		// Arrays may continue over several lines until the brackets balance.
+		if strings.HasPrefix(rawValue, "[") {
+			for !bracketsBalanced(rawValue) && n+1 < len(lines) {
+				n++
+				rawValue += " " + strings.TrimSpace(stripComment(lines[n]))
+			}
+		}
+
+		values, err := parseInlineValue(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		key := strings.Join(keyParts, ".")
+		if section != "" {
+			key = section + "." + key
+		}
+		doc.set(key, values, lineNo)
+	}
+	return doc, nil
+}
+
+// ── YAML ─────────────────────────────────────
+
+func parseYAMLDoc(text string) (*confDoc, error) {
+	doc := &confDoc{}
+
+	type frame struct {
+		indent int
+		key    string
+	}
+	var stack []frame
+
+	// A "key:" line with no value opens either a nested mapping or a block
+	// list; listKey remembers it until the next line tells which.
+	listKey := ""
+	listLine := 0
+	var listValues []string
+	flushList := func() {
+		if listKey != "" && len(listValues) > 0 {
+			doc.set(listKey, listValues, listLine)
+		}
+		listKey, listValues = "", nil
+	}
+
+	for n, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		lineNo := n + 1
+		if strings.TrimSpace(raw) == "---" {
+			continue
+		}
+		line := strings.TrimRight(stripComment(raw), " \t")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+		indent := len(line) - len(trimmed)
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a list", lineNo)
+			}
+			item, err := parseScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			listValues = append(listValues, item)
+			continue
+		}
+		flushList()
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		rawKey, rawValue, ok := cutUnquoted(trimmed, ':')
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", lineNo)
+		}
+		key, err := parseScalar(strings.TrimSpace(rawKey))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		full := key
+		if len(stack) > 0 {
+			full = stack[len(stack)-1].key + "." + key
+		}
+
+		rawValue = strings.TrimSpace(rawValue)
+		if rawValue == "" {
+			stack = append(stack, frame{indent: indent, key: full})
+			listKey, listLine = full, lineNo
+			continue
+		}
+		values, err := parseInlineValue(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		doc.set(full, values, lineNo)
+	}
+	flushList()
+	return doc, nil
+}
+
+// ── shared helpers ───────────────────────────
+
+// parseInlineValue parses a scalar or a [flow, array].
+func parseInlineValue(s string) ([]string, error) {
+	if !strings.HasPrefix(s, "[") {
+		v, err := parseScalar(s)
+		if err != nil {
+			return nil, err
+		}
+		return []string{v}, nil
+	}
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("unterminated array")
+	}
+	body := strings.TrimSpace(s[1 : len(s)-1])
+	values := []string{}
+	for body != "" {
+		item, rest, _ := cutUnquoted(body, ',')
+		item = strings.TrimSpace(item)
+		if item != "" {
+			v, err := parseScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		body = strings.TrimSpace(rest)
+	}
+	return values, nil
+}
+
+// parseScalar unquotes "basic" and 'literal' strings; bare words are
+// returned as-is.
+func parseScalar(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	if len(s) >= 1 && s[0] == '"' {
+		if len(s) < 2 || s[len(s)-1] != '"' {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		var b strings.Builder
+		body := s[1 : len(s)-1]
+		for i := 0; i < len(body); i++ {
+			c := body[i]
+			if c != '\\' || i+1 == len(body) {
+				b.WriteByte(c)
+				continue
+			}
+			i++
+			switch body[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'e':
+				b.WriteByte('\033')
+			default:
+				b.WriteByte(body[i])
+			}
+		}
+		return b.String(), nil
+	}
+	if strings.HasPrefix(s, "'") {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	return s, nil
+}
+
+// splitDottedKey splits a.b."c.d" into its parts.
+func splitDottedKey(s string) ([]string, error) {
+	var parts []string
+	for s != "" {
+		part, rest, _ := cutUnquoted(s, '.')
+		v, err := parseScalar(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if v == "" && !strings.Contains(part, `"`) && !strings.Contains(part, "'") {
+			return nil, fmt.Errorf("empty key")
+		}
+		parts = append(parts, v)
+		s = rest
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+	return parts, nil
+}
+
+// cutUnquoted is strings.Cut that ignores separators inside quotes.
+func cutUnquoted(s string, sep byte) (before, after string, found bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// stripComment removes a trailing # comment that is not inside quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func bracketsBalanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
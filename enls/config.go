@@ -0,0 +1,283 @@
+package enls
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// Configuration file
+// ─────────────────────────────────────────────
+//
+// Defaults are read from $ENLS_CONFIG or, failing that, from
+// config.toml / config.yaml in the enls directory under the user config
+// directory ($XDG_CONFIG_HOME/enls, ~/.config/enls or %AppData%\enls).
+// Top-level settings are turned into command-line arguments that are placed
+// before $ENLS_OPTS and the real arguments, so later sources win. Category
+// and classification tables are described in categories.go.
+//
+//	flags   = ["-c", "-f"]
+//	sort    = "time"
+//	reverse = false
+//	columns = ["name", "mode", "size", "modified"]
+//	theme   = "solarized"
+//	color   = "auto"
+//	color_depth = "256"
+//	ignore  = ["*.pyc", "__pycache__"]
+//
+//	[extensions]
+//	".tfstate" = "backup"
+
+// Config is the parsed configuration file.
+type Config struct {
+	Path           string
+	Args           []string
+	ExtensionTypes map[string]FileType
+	NameTypes      map[string]FileType
+	GlobTypes      []globRule
+}
+
+var configFileNames = []string{"config.toml", "config.yaml", "config.yml"}
+
+// configTypeNames are the type names accepted by the classification tables;
+// directories and symbolic links are never decided by name. Categories are
+// added by registerCategory.
+var configTypeNames = map[string]FileType{
+	"executable": FileTypeExecutable,
+	"archive":    FileTypeArchive,
+	"media":      FileTypeMedia,
+	"backup":     FileTypeBackup,
+	"other":      FileTypeOther,
+}
+
+// configDir returns the enls directory under the user config directory.
+func configDir() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		var err error
+		if base, err = os.UserConfigDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(base, "enls"), nil
+}
+
+// findConfigFile returns the config file path, or "" when there is none.
+func findConfigFile() (string, error) {
+	if p := os.Getenv("ENLS_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", nil
+	}
+	for _, name := range configFileNames {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// loadConfig reads the config file if there is one.
+func loadConfig() (*Config, error) {
+	path, err := findConfigFile()
+	if err != nil || path == "" {
+		return nil, err
+	}
+	doc, err := readConfDoc(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Path:           path,
+		ExtensionTypes: make(map[string]FileType),
+		NameTypes:      make(map[string]FileType),
+	}
+	fail := func(key string, format string, a ...any) (*Config, error) {
+		return nil, fmt.Errorf("%s: %s: %s", path, key, fmt.Sprintf(format, a...))
+	}
+
+	// Categories come first so the tables below may refer to them
+	// regardless of their order in the file.
+	catKeys := map[string]map[string]string{}
+	var catOrder []string
+	for _, e := range doc.Section("categories") {
+		name, field, ok := strings.Cut(e.Key, ".")
+		if !ok || len(e.Values) != 1 {
+			return fail("categories."+e.Key, "expected categories.NAME.indicator or .color")
+		}
+		if _, seen := catKeys[name]; !seen {
+			catKeys[name] = map[string]string{}
+			catOrder = append(catOrder, name)
+		}
+		catKeys[name][field] = e.Values[0]
+	}
+	for _, name := range catOrder {
+		fields := catKeys[name]
+		for field := range fields {
+			if field != "indicator" && field != "color" {
+				return fail("categories."+name+"."+field, "unknown setting")
+			}
+		}
+		st, err := parseStyle(fields["color"])
+		if err != nil {
+			return fail("categories."+name+".color", "%v", err)
+		}
+		if _, err := registerCategory(name, fields["indicator"], st); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	typeValue := func(e confEntry) (FileType, error) {
+		if len(e.Values) != 1 {
+			return FileTypeOther, fmt.Errorf("expected a type name")
+		}
+		ft, ok := configTypeNames[strings.ToLower(e.Values[0])]
+		if !ok {
+			return FileTypeOther, fmt.Errorf("unknown type %q", e.Values[0])
+		}
+		return ft, nil
+	}
+
+	for _, e := range doc.Entries {
+		key := e.Key
+		switch {
+		case key == "flags":
+			for _, f := range e.Values {
+				if !strings.HasPrefix(f, "-") {
+					return fail(key, "%q is not an option", f)
+				}
+				cfg.Args = append(cfg.Args, f)
+			}
+		case key == "sort" || key == "theme" || key == "color" || key == "color_depth":
+			if len(e.Values) != 1 {
+				return fail(key, "expected a single value")
+			}
+			cfg.Args = append(cfg.Args, "--"+strings.ReplaceAll(key, "_", "-")+"="+e.Values[0])
+		case key == "reverse":
+			if len(e.Values) == 1 && e.Values[0] == "true" {
+				cfg.Args = append(cfg.Args, "--reverse")
+			} else if len(e.Values) != 1 || e.Values[0] != "false" {
+				return fail(key, "expected true or false")
+			}
+		case key == "columns":
+			cfg.Args = append(cfg.Args, "--columns="+strings.Join(e.Values, ","))
+		case key == "ignore":
+			for _, p := range e.Values {
+				cfg.Args = append(cfg.Args, "--ignore="+p)
+			}
+		case strings.HasPrefix(key, "categories."):
+			// Handled above.
+		case strings.HasPrefix(key, "extensions."):
+			ext := strings.ToLower(strings.TrimPrefix(key, "extensions."))
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			ft, err := typeValue(e)
+			if err != nil {
+				return fail(key, "%v", err)
+			}
+			cfg.ExtensionTypes[ext] = ft
+		case strings.HasPrefix(key, "filenames."):
+			ft, err := typeValue(e)
+			if err != nil {
+				return fail(key, "%v", err)
+			}
+			cfg.NameTypes[strings.TrimPrefix(key, "filenames.")] = ft
+		case strings.HasPrefix(key, "globs."):
+			pattern := strings.TrimPrefix(key, "globs.")
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fail(key, "invalid pattern: %v", err)
+			}
+			ft, err := typeValue(e)
+			if err != nil {
+				return fail(key, "%v", err)
+			}
+			cfg.GlobTypes = append(cfg.GlobTypes, globRule{pattern: pattern, ft: ft})
+		default:
+			return fail(key, "unknown setting (line %d)", e.Line)
+		}
+	}
+	return cfg, nil
+}
+
+// ApplyRules installs the classification tables used by getFileType.
+func (cfg *Config) ApplyRules() {
+	for ext, ft := range cfg.ExtensionTypes {
+		userExtTypes[ext] = ft
+	}
+	for name, ft := range cfg.NameTypes {
+		nameRules[name] = ft
+	}
+	globRules = append(globRules, cfg.GlobTypes...)
+}
+
+// splitCommandLine splits ENLS_OPTS into arguments, honouring single and
+// double quotes so patterns with spaces survive.
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in ENLS_OPTS")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// DefaultArgs returns the arguments contributed by the config file and
+// ENLS_OPTS, unless --no-config appears on the command line.
+func DefaultArgs(cmdline []string) ([]string, *Config, error) {
+	for _, a := range cmdline {
+		if a == "--no-config" {
+			return nil, nil, nil
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	var out []string
+	if cfg != nil {
+		out = append(out, cfg.Args...)
+	}
+	env, err := splitCommandLine(os.Getenv("ENLS_OPTS"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(out, env...), cfg, nil
+}
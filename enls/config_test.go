@@ -0,0 +1,107 @@
+package enls
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		file string // config.toml or config.yaml
+		text string
+		args []string
+		exts map[string]FileType
+		err  string // substring of the error, "" for none
+	}{
+		{
+			name: "toml",
+			file: "config.toml",
+			text: `
+flags   = ["-c", "-f"]
+sort    = "time"
+reverse = true
+columns = ["name", "size"]
+ignore  = ["*.pyc"]
+color_depth = "256"
+
+[extensions]
+".tfstate" = "backup"
+bin = "executable"
+`,
+			args: []string{"-c", "-f", "--sort=time", "--reverse", "--columns=name,size", "--ignore=*.pyc", "--color-depth=256"},
+			exts: map[string]FileType{".tfstate": FileTypeBackup, ".bin": FileTypeExecutable},
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			text: "flags: [-l]\ntheme: light\nextensions:\n  .iso: archive\n",
+			args: []string{"-l", "--theme=light"},
+			exts: map[string]FileType{".iso": FileTypeArchive},
+		},
+		{name: "reverse false", file: "config.toml", text: "reverse = false\n"},
+		{name: "unknown key", file: "config.toml", text: "sort = \"name\"\nsrot = \"time\"\n", err: "srot: unknown setting (line 2)"},
+		{name: "unknown section", file: "config.toml", text: "[colours]\ndirectory = \"blue\"\n", err: "colours.directory: unknown setting"},
+		{name: "flag without dash", file: "config.toml", text: "flags = [\"l\"]\n", err: `"l" is not an option`},
+		{name: "reverse not bool", file: "config.toml", text: "reverse = \"yes\"\n", err: "expected true or false"},
+		{name: "unknown type", file: "config.toml", text: "[extensions]\n\".x\" = \"video\"\n", err: `unknown type "video"`},
+		{name: "bad glob", file: "config.toml", text: "[globs]\n\"[\" = \"media\"\n", err: "invalid pattern"},
+		{name: "syntax", file: "config.toml", text: "sort\n", err: "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.text), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("ENLS_CONFIG", path)
+
+			cfg, err := loadConfig()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.Args, tt.args) {
+				t.Errorf("args %q, want %q", cfg.Args, tt.args)
+			}
+			for ext, ft := range tt.exts {
+				if got, ok := cfg.ExtensionTypes[ext]; !ok || got != ft {
+					t.Errorf("extension %s: %v, %v; want %v", ext, got, ok, ft)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{"-l -a", []string{"-l", "-a"}, false},
+		{`--ignore="*.o" -c`, []string{"--ignore=*.o", "-c"}, false},
+		{`--theme='my theme'`, []string{"--theme=my theme"}, false},
+		{`"unterminated`, nil, true},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := splitCommandLine(tt.in)
+			if (err != nil) != tt.err {
+				t.Fatalf("error %v, want error %v", err, tt.err)
+			}
+			if !tt.err && !slices.Equal(got, tt.want) {
+				t.Errorf("%q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1792 @@
+package enls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ─────────────────────────────────────────────
+// Types & constants
+// ─────────────────────────────────────────────
+
+type FileType int
+
+const (
+	FileTypeOther FileType = iota
+	FileTypeDirectory
+	FileTypeExecutable
+	FileTypeSymbolicLink
+	FileTypeArchive
+	FileTypeMedia
+	FileTypeBackup
+)
+
+// ColorMode is the tri-state value of --color.
+type ColorMode int
+
+const (
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+// TTYMode is the value of --tty: whether stdout is treated as a terminal.
+type TTYMode int
+
+const (
+	TTYAuto TTYMode = iota
+	TTYYes
+	TTYNo
+)
+
+// ValidTypeIndicators is the canonical set of built-in filter characters;
+// categories from the config file add their own (see isTypeIndicator).
+const ValidTypeIndicators = "/*@#~%"
+
+var (
+	executableExtensions = []string{
+		".appx", ".exe", ".com", ".bat", ".cmd", ".ps1", ".vbs",
+		".msi", ".msix", ".msixbundle", ".msm", ".msp", ".mst",
+		".cpl", ".wsf", ".psm1", ".elf", ".bash", ".zsh", ".php",
+		".scr", ".app", ".command", ".workflow", ".ts", ".wasm",
+		".sh", ".out", ".bin", ".run", ".desktop", ".reg", ".ipa",
+		".py", ".rb", ".pl", ".js", ".jar", ".lua", ".ahk",
+		".msc", ".jse", ".vbe", ".scpt", ".pif", ".gadget",
+	}
+	archiveExtensions = []string{
+		".7z", ".zip", ".rar", ".tar", ".gz", ".xz", ".bz2",
+		".cab", ".img", ".iso", ".pea", ".rpm", ".tgz", ".qcow2",
+		".z", ".deb", ".arj", ".lzh", ".lzma", ".lzma2", ".zipx",
+		".war", ".zst", ".part", ".s7z", ".split", ".aar", ".br",
+		".wim", ".esd", ".apk", ".dmg", ".pkg", ".ear", ".lz",
+		".crx", ".xpi", ".cpio", ".lha", ".sitx", ".vmdk",
+	}
+	mediaExtensions = []string{
+		".aac", ".amr", ".caf", ".m3u", ".midi", ".mod",
+		".mp1", ".mp2", ".mp3", ".ogg", ".opus", ".ra", ".wma",
+		".wav", ".wv", ".m4a", ".flac", ".alac", ".aiff", ".ape",
+		".3gp", ".3g2", ".asf", ".avi", ".flv", ".m4v", ".mkv",
+		".mov", ".mp4", ".mpeg", ".mpg", ".mpe", ".mts", ".rm",
+		".rmvb", ".swf", ".vob", ".webm", ".wmv", ".ogv", ".m2ts",
+		".ai", ".art", ".blend", ".cgm", ".cin", ".cur", ".cut",
+		".dcx", ".dng", ".dpx", ".emf", ".fit", ".fits", ".fpx",
+		".g3", ".hdr", ".ief", ".jbig", ".jfif", ".jls", ".jp2",
+		".jpc", ".jpx", ".jpg", ".jpeg", ".jxl", ".raw", ".cr2",
+		".pbm", ".pcd", ".pcx", ".pgm", ".pict", ".png", ".pnm",
+		".ppm", ".psd", ".ras", ".rgb", ".svg", ".tga", ".tif", ".gif",
+		".tiff", ".wbmp", ".xpm", ".bmp", ".webp", ".avif", ".ico",
+		".heic", ".heif", ".nef", ".arw", ".psb", ".glb", ".gltf",
+	}
+	backupExtensions = []string{
+		".bak", ".backup", ".orig", ".old", ".tmp", ".temp",
+		".swap", ".chklist", ".chk", ".ms", ".diz", ".wbk",
+		".xlk", ".cdr_", ".nch", ".ftg", ".gid", ".syd",
+		".bkp", ".gho", ".vhd", ".vhdx", ".tib", ".log",
+		".sql", ".dump", ".sav", ".dbk", ".rdb",
+	}
+
+	// extTypeMap is built in init() for O(1) extension lookups.
+	extTypeMap map[string]FileType
+
+	ansiReset = "\033[0m"
+	colorMap  = map[FileType]string{
+		FileTypeDirectory:    "\033[94m",
+		FileTypeExecutable:   "\033[32m",
+		FileTypeSymbolicLink: "\033[96m",
+		FileTypeArchive:      "\033[91m",
+		FileTypeMedia:        "\033[95m",
+		FileTypeBackup:       "\033[90m",
+		FileTypeOther:        "",
+	}
+
+	typeIndicators = map[FileType]string{
+		FileTypeDirectory:    "/",
+		FileTypeExecutable:   "*",
+		FileTypeSymbolicLink: "@",
+		FileTypeArchive:      "#",
+		FileTypeMedia:        "~",
+		FileTypeBackup:       "%",
+		FileTypeOther:        "",
+	}
+
+	spaceLength = 2
+	currentUser = "user"
+
+	// colorEnabled is resolved once in main from --color and the
+	// environment; every escape-sequence emitter consults it.
+	colorEnabled bool
+
+	// fixedNow, outputWidth and ttyMode come from --now, --width and
+	// --tty, for reproducible output.
+	fixedNow    time.Time
+	outputWidth int
+	ttyMode     TTYMode
+)
+
+// ─────────────────────────────────────────────
+// init
+// ─────────────────────────────────────────────
+
+func init() {
+	// Resolve current OS user.
+	if u, err := user.Current(); err == nil {
+		currentUser = u.Username
+	}
+
+	// Build extension → FileType map for O(1) lookups.
+	extTypeMap = make(map[string]FileType)
+	for _, e := range backupExtensions {
+		extTypeMap[e] = FileTypeBackup
+	}
+	for _, e := range mediaExtensions {
+		extTypeMap[e] = FileTypeMedia
+	}
+	for _, e := range archiveExtensions {
+		extTypeMap[e] = FileTypeArchive
+	}
+	// Executable extensions are only used on Windows but we store them
+	// unconditionally and gate the lookup at call-time.
+	for _, e := range executableExtensions {
+		if _, exists := extTypeMap[e]; !exists {
+			extTypeMap[e] = FileTypeExecutable
+		}
+	}
+}
+
+// ─────────────────────────────────────────────
+// Argument types
+// ─────────────────────────────────────────────
+
+// Options mirrors the command-line options; ParseArgs fills it in.
+type Options struct {
+	Path          string
+	LongFormat    bool
+	ShowFileType  bool
+	SetColor      bool
+	ColorMode     ColorMode
+	ColorDepth    ColorDepth
+	Theme         string
+	ShowHelp      bool
+	SearchTerm    string
+	IgnoreCase    bool
+	StrictCase    bool
+	FilterType    string
+	Recursive     bool
+	ShowAll       bool // -a: show hidden (dot) files
+	Sort          string
+	Reverse       bool
+	Columns       []string
+	Ignore        []string
+	NoConfig      bool
+	Sniff         bool
+	SniffLimit    int
+	MIMEPrefix    string
+	Arch          string
+	Archive       bool
+	XAttr         bool
+	Context       bool // -Z: SELinux security context
+	Audit         bool
+	NumericIDs    bool // -n: numeric uid/gid, implies -l
+	ExplainMode   bool
+	ExplainPath   string
+	Stream        bool // -U: unsorted, printed batch by batch
+	MaxEntries    int
+	Timeout       time.Duration
+	Now           time.Time // --now: the clock for relative times; zero is the real one
+	Width         int       // --width: 0 means $COLUMNS or the terminal
+	TTY           TTYMode
+	AmbiguousWide bool     // --ambiguous-width=2
+	Warnings      []string // problems ParseArgs worked around, for the caller to report
+}
+
+// Entry is one listed file with its owner and link count resolved.
+type Entry struct {
+	fs.FileInfo
+	Path      string
+	Links     uint64
+	OwnerName string
+	GroupName string
+
+	lister  *Lister       // the Lister that listed the entry
+	archive *archiveIndex // the archive Path lies in, if any
+}
+
+// ─────────────────────────────────────────────
+// Terminal / display utilities
+// ─────────────────────────────────────────────
+
+func isOutputRedirected() bool {
+	switch ttyMode {
+	case TTYYes:
+		return false
+	case TTYNo:
+		return true
+	}
+	stat, err := os.Stdout.Stat()
+	if err != nil {
+		return true
+	}
+	return (stat.Mode() & os.ModeCharDevice) == 0
+}
+
+// resolveColorMode decides whether escape sequences may be written to
+// stdout. An explicit always/never wins; auto honours NO_COLOR, then
+// CLICOLOR_FORCE, CLICOLOR=0 and TERM=dumb before falling back to
+// whether stdout is a terminal.
+func resolveColorMode(mode ColorMode) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v := os.Getenv("CLICOLOR_FORCE"); v != "" && v != "0" {
+		return true
+	}
+	if os.Getenv("CLICOLOR") == "0" {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return !isOutputRedirected()
+}
+
+func parseColorMode(value string) (ColorMode, error) {
+	switch value {
+	case "", "always", "yes", "force":
+		return ColorAlways, nil
+	case "auto", "tty", "if-tty":
+		return ColorAuto, nil
+	case "never", "no", "none":
+		return ColorNever, nil
+	}
+	return ColorAuto, fmt.Errorf("invalid argument %q for --color (valid: auto, always, never)", value)
+}
+
+func parseTTYMode(value string) (TTYMode, error) {
+	switch value {
+	case "yes", "always":
+		return TTYYes, nil
+	case "no", "never":
+		return TTYNo, nil
+	case "auto":
+		return TTYAuto, nil
+	}
+	return TTYAuto, fmt.Errorf("invalid argument %q for --tty (valid: yes, no, auto)", value)
+}
+
+// parseTimestamp reads --now: RFC 3339, "2006-01-02 15:04:05",
+// "2006-01-02" (local time) or "@UNIXSECONDS".
+func parseTimestamp(value string) (time.Time, error) {
+	if secs, ok := strings.CutPrefix(value, "@"); ok {
+		n, err := strconv.ParseInt(secs, 10, 64)
+		if err == nil {
+			return time.Unix(n, 0), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid argument %q for --now (expected e.g. 2024-05-01T12:00:00Z, 2024-05-01 or @1714564800)", value)
+}
+
+// currentTime is the clock relative times are measured against.
+func currentTime() time.Time {
+	if !fixedNow.IsZero() {
+		return fixedNow
+	}
+	return time.Now()
+}
+
+func getTerminalWidth() int {
+	if outputWidth > 0 {
+		return outputWidth
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	// If output is redirected there is no terminal; use a very large value so
+	// nothing gets truncated by column arithmetic.
+	if isOutputRedirected() {
+		return math.MaxInt
+	}
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
+
+func padByWidth(s string, totalWidth int) string {
+	padding := totalWidth - getStringDisplayWidth(s)
+	if padding <= 0 {
+		return s
+	}
+	return s + strings.Repeat(" ", padding)
+}
+
+func padLeftByWidth(s string, totalWidth int) string {
+	padding := totalWidth - getStringDisplayWidth(s)
+	if padding <= 0 {
+		return s
+	}
+	return strings.Repeat(" ", padding) + s
+}
+
+func centerByWidth(s string, totalWidth int) string {
+	padding := totalWidth - getStringDisplayWidth(s)
+	if padding <= 0 {
+		return s
+	}
+	left := padding / 2
+	right := padding - left
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", right)
+}
+
+// ─────────────────────────────────────────────
+// Hyperlink / gradient helpers (cosmetic)
+// ─────────────────────────────────────────────
+
+func addGradient(text string, startRGB, endRGB [3]int) string {
+	if !colorEnabled {
+		return text
+	}
+	result := ""
+	chars := []rune(text)
+	for i, char := range chars {
+		ratio := float64(i) / float64(len(chars)-1)
+		if len(chars) == 1 {
+			ratio = 0
+		}
+		r := int(float64(startRGB[0]) + (float64(endRGB[0])-float64(startRGB[0]))*ratio)
+		g := int(float64(startRGB[1]) + (float64(endRGB[1])-float64(startRGB[1]))*ratio)
+		b := int(float64(startRGB[2]) + (float64(endRGB[2])-float64(startRGB[2]))*ratio)
+		st := Style{FG: Color{kind: colorRGB, r: uint8(r), g: uint8(g), b: uint8(b)}}
+		result += st.Seq() + string(char)
+	}
+	return result + ansiReset
+}
+
+func createHyperlink(text, url string) string {
+	if !colorEnabled {
+		return text
+	}
+	return fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", url, text)
+}
+
+// ─────────────────────────────────────────────
+// Help text
+// ─────────────────────────────────────────────
+
+// HelpText returns the --help text.
+func HelpText() string {
+	startRGB := [3]int{0, 150, 255}
+	endRGB := [3]int{50, 255, 50}
+	gradientTitle := addGradient("Enhanced-ls v0.1.3 (Cross-Platform)", startRGB, endRGB)
+	link := createHyperlink(gradientTitle, "https://github.com/Geekstrange/enhanced-ls")
+
+	reset := ansiReset
+	cyan := "\033[96m"
+	green := "\033[32m"
+	blue := "\033[94m"
+	yellow := "\033[93m"
+	if !colorEnabled {
+		reset, cyan, green, blue, yellow = "", "", "", "", ""
+	}
+
+	return fmt.Sprintf(`
+        %s
+
+%sOptions:%s
+    %s-f%s        append indicator (one of */@/#~/%%) to entries.
+    %s-f id%s     only show entries of specified type (id: one of */@/#~/%%)
+    %s-a%s        show hidden files (entries starting with '.').
+    %s-c%s        color the output (same as --color=auto).
+    %s--color=WHEN%s  colorize: auto, always or never (honours NO_COLOR,
+              CLICOLOR, CLICOLOR_FORCE and TERM=dumb in auto mode).
+    %s--theme=NAME%s  color theme: dark, light, solarized or a .toml/.yaml file.
+    %s--color-depth=N%s  palette: auto, 16, 256 or truecolor.
+    %s--sort=KEY%s    sort by name, size, time, extension or none.
+    %s--reverse%s     reverse the sort order.
+    %s--columns=LIST%s  long-format columns, e.g. name,mode,size,modified;
+              "media" adds image dimensions and audio/video duration,
+              "archive" the file count and unpacked size of archives,
+              "lines", "encoding" and "eol" describe text files.
+    %s--xattr%s       list extended attributes under each entry; in -l the
+              mode shows "+" for POSIX ACLs and "@" for other xattrs,
+              and the "caps" column decodes file capabilities;
+              "attrs" shows chattr flags such as immutable.
+    %s--audit%s       report world-writable, setuid/setgid, unowned and
+              group-writable executable files and dangling symlinks
+              under PATH; exits 1 when anything is found.
+    %s--explain-mode[=FILE]%s  explain the permissions of FILE (or PATH)
+              in words, e.g. "owner: read, write". The "octal" column
+              shows modes as 0755, "access" what you can actually do
+              (checked with access(2), so groups, ACLs and read-only
+              mounts count).
+    %s--archive%s     list the contents of the archive PATH (zip, tar,
+              tar.gz, tar.bz2); "release.zip/" does the same.
+    %s--ignore=GLOB%s do not list entries matching GLOB (repeatable).
+    %s--no-config%s   ignore the config file and ENLS_OPTS.
+    %s--sniff[=N]%s   detect file types from content, reading at most N
+              files per directory (default 1000).
+    %s--mime PREFIX%s only show entries whose MIME type starts with PREFIX;
+              add "mime" to --columns to show the type.
+    %s--arch TARGET%s only show ELF/PE/Mach-O binaries for TARGET (linux,
+              arm64, windows/amd64, ...); the "arch" column shows
+              OS/arch, bitness, linking and stripped status.
+    %s-n%s        like -l, but list numeric user and group ids.
+    %s--max-entries N%s  stop -r and --audit after N entries.
+    %s--timeout D%s   stop -r and --audit after duration D (e.g. 30s);
+              a truncated walk is reported on stderr and exits 1.
+    %s--now=TIME%s    measure relative times from TIME (2024-05-01,
+              2024-05-01T12:00:00Z or @UNIXSECONDS), not the clock.
+    %s--width=N%s     assume an N-column terminal (default: $COLUMNS, then
+              the terminal size).
+    %s--tty=WHEN%s    treat stdout as a terminal: yes, no or auto; affects
+              --color=auto and the layout width.
+    %s--ambiguous-width=N%s  columns for East Asian Ambiguous characters
+              such as "①" or "→": 1 (default) or 2 for CJK terminals.
+    %s-U%s        do not sort; print entries while reading the directory
+              (one per line, or -l without borders). Same as --stream.
+    %s-Z%s        print the SELinux security context of each entry.
+    %s-l%s        display items in a formatted table with borders.
+    %s-r%s        recursively list subdirectories (tree view).
+    %s-s%s        search files (case-insensitive).
+    %s-S%s        search files (case-sensitive).
+    %s-h%s        display this help message.
+
+%sFile Type Indicators:%s
+    %s/%s         Directory
+    %s*%s         Executable
+    %s@%s         Symbolic Link
+    %s#%s         Archive (compressed file)
+    %s~%s         Media file (audio/video/image)
+    %s%%%s         Backup/Temporary file
+%s
+%sExamples:%s
+    %s-f%s        Show all files with type indicators
+    %s-f #%s      Show only archive files
+    %s-f *%s      Show only executables
+    %s-fc @%s     Show symbolic links with color
+    %s-r%s        Recursive directory listing (tree view)
+    %s-r -s go%s  Recursive search for "go" (case-insensitive)
+    %s-r -S Go%s  Recursive search for "Go" (case-sensitive)
+
+%sExit Status:%s
+    %s0%s         OK
+    %s1%s         Minor problems (e.g. unreadable subdirectory, truncated walk)
+    %s2%s         Serious trouble (e.g. bad option, inaccessible path)
+    %s130%s       Interrupted
+
+%sSupported Platforms:%s
+    %s- Windows%s x86_64/ARM64
+    %s- Linux%s   x86_64/ARM64/LoongArch
+    %s- macOS%s   x86_64/ARM64
+`,
+		link,
+		cyan, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		green, reset,
+		cyan, reset,
+		blue, reset,
+		blue, reset,
+		blue, reset,
+		blue, reset,
+		blue, reset,
+		blue, reset,
+		blue, reset,
+		categoryHelp(blue, reset),
+		cyan, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+		cyan, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+		cyan, reset,
+		yellow, reset,
+		yellow, reset,
+		yellow, reset,
+	)
+}
+
+// ─────────────────────────────────────────────
+// Argument parsing
+// ─────────────────────────────────────────────
+
+// ParseArgs parses enls command-line arguments.
+func ParseArgs(args []string) (*Options, error) {
+	lsArgs := &Options{Path: "."}
+
+	validOptions := "faclrSsShZnU"
+
+	i := 0
+	for i < len(args) {
+		arg := args[i]
+
+		if arg == "-h" || arg == "--help" {
+			lsArgs.ShowHelp = true
+			return lsArgs, nil
+		}
+
+		if strings.HasPrefix(arg, "--") {
+			opt := arg[2:]
+			// "--name value" for options that always take an argument.
+			if !strings.Contains(opt, "=") && longOptionsWithValue[opt] && i < len(args)-1 {
+				i++
+				opt += "=" + args[i]
+			}
+			if err := parseLongOption(lsArgs, opt); err != nil {
+				return nil, err
+			}
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(arg, "-") {
+			options := arg[1:]
+			if options == "" {
+				return lsArgs, fmt.Errorf("invalid option: %s", arg)
+			}
+
+			for _, r := range options {
+				if !strings.ContainsRune(validOptions, r) {
+					return nil, fmt.Errorf("invalid option -- '%c'", r)
+				}
+			}
+
+			// Enforce mutual exclusivity of -s and -S.
+			hasS := strings.ContainsRune(options, 'S')
+			hass := strings.ContainsRune(options, 's')
+			if hasS && hass {
+				return nil, fmt.Errorf("-s (case-insensitive) and -S (case-sensitive) are mutually exclusive")
+			}
+
+			if hasS {
+				lsArgs.StrictCase = true
+				if i < len(args)-1 && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					lsArgs.SearchTerm = args[i]
+				}
+			} else if hass {
+				lsArgs.IgnoreCase = true
+				if i < len(args)-1 && !strings.HasPrefix(args[i+1], "-") {
+					i++
+					lsArgs.SearchTerm = args[i]
+				}
+			} else {
+				for _, r := range options {
+					switch r {
+					case 'l':
+						lsArgs.LongFormat = true
+					case 'f':
+						lsArgs.ShowFileType = true
+						if i < len(args)-1 && !strings.HasPrefix(args[i+1], "-") {
+							next := args[i+1]
+							if isTypeIndicator(next) {
+								lsArgs.FilterType = next
+								i++
+							}
+						}
+					case 'c':
+						lsArgs.SetColor = true
+						lsArgs.ColorMode = ColorAuto
+					case 'r':
+						lsArgs.Recursive = true
+					case 'a':
+						lsArgs.ShowAll = true
+					case 'Z':
+						lsArgs.Context = true
+					case 'n':
+						lsArgs.NumericIDs = true
+						lsArgs.LongFormat = true
+					case 'U':
+						lsArgs.Stream = true
+					}
+				}
+			}
+		} else {
+			// Positional path argument.
+			if lsArgs.Path == "." {
+				lsArgs.Path = arg
+			} else {
+				lsArgs.Warnings = append(lsArgs.Warnings, "multiple paths not supported, using first path: "+lsArgs.Path)
+			}
+		}
+		i++
+	}
+
+	return lsArgs, nil
+}
+
+// longOptionsWithValue lists the long options whose argument may also be
+// given as the following command-line word.
+var longOptionsWithValue = map[string]bool{
+	"theme":           true,
+	"color-depth":     true,
+	"sort":            true,
+	"columns":         true,
+	"ignore":          true,
+	"mime":            true,
+	"arch":            true,
+	"max-entries":     true,
+	"timeout":         true,
+	"now":             true,
+	"width":           true,
+	"tty":             true,
+	"ambiguous-width": true,
+}
+
+// parseLongOption handles a single "--name" or "--name=value" argument.
+func parseLongOption(lsArgs *Options, opt string) error {
+	name, value, _ := strings.Cut(opt, "=")
+	switch name {
+	case "color", "colour":
+		mode, err := parseColorMode(value)
+		if err != nil {
+			return err
+		}
+		lsArgs.ColorMode = mode
+		lsArgs.SetColor = mode != ColorNever
+	case "now":
+		t, err := parseTimestamp(value)
+		if err != nil {
+			return err
+		}
+		lsArgs.Now = t
+	case "width":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid argument %q for --width (expected a positive number of columns)", value)
+		}
+		lsArgs.Width = n
+	case "tty":
+		mode, err := parseTTYMode(value)
+		if err != nil {
+			return err
+		}
+		lsArgs.TTY = mode
+	case "ambiguous-width":
+		switch value {
+		case "1", "narrow":
+			lsArgs.AmbiguousWide = false
+		case "2", "wide":
+			lsArgs.AmbiguousWide = true
+		default:
+			return fmt.Errorf("invalid argument %q for --ambiguous-width (valid: 1, 2)", value)
+		}
+	case "color-depth":
+		depth, err := parseColorDepth(value)
+		if err != nil {
+			return err
+		}
+		lsArgs.ColorDepth = depth
+	case "theme":
+		if value == "" {
+			return fmt.Errorf("option '--theme' requires an argument")
+		}
+		lsArgs.Theme = value
+	case "sort":
+		if !containsString(validSortKeys, value) {
+			return fmt.Errorf("invalid argument %q for --sort (valid: %s)", value, strings.Join(validSortKeys, ", "))
+		}
+		lsArgs.Sort = value
+	case "reverse":
+		lsArgs.Reverse = true
+	case "columns":
+		cols, err := parseColumnList(value)
+		if err != nil {
+			return err
+		}
+		lsArgs.Columns = cols
+	case "ignore":
+		if value == "" {
+			return fmt.Errorf("option '--ignore' requires a pattern")
+		}
+		if _, err := filepath.Match(value, ""); err != nil {
+			return fmt.Errorf("invalid --ignore pattern %q: %v", value, err)
+		}
+		lsArgs.Ignore = append(lsArgs.Ignore, value)
+	case "no-config":
+		lsArgs.NoConfig = true
+	case "archive":
+		lsArgs.Archive = true
+	case "xattr":
+		lsArgs.XAttr = true
+	case "audit":
+		lsArgs.Audit = true
+	case "stream":
+		lsArgs.Stream = true
+	case "max-entries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid argument %q for --max-entries (expected a positive count)", value)
+		}
+		lsArgs.MaxEntries = n
+	case "timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			// A bare number means seconds.
+			if secs, aerr := strconv.ParseFloat(value, 64); aerr == nil {
+				d, err = time.Duration(secs*float64(time.Second)), nil
+			}
+		}
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid argument %q for --timeout (expected a duration such as 30s or 2m)", value)
+		}
+		lsArgs.Timeout = d
+	case "explain-mode":
+		lsArgs.ExplainMode = true
+		lsArgs.ExplainPath = value
+	case "mime":
+		if value == "" {
+			return fmt.Errorf("option '--mime' requires a type prefix such as image/")
+		}
+		lsArgs.MIMEPrefix = strings.ToLower(value)
+	case "arch":
+		if value == "" {
+			return fmt.Errorf("option '--arch' requires an OS, architecture or OS/ARCH")
+		}
+		lsArgs.Arch = value
+	case "sniff":
+		lsArgs.Sniff = true
+		if value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid argument %q for --sniff (expected a positive file count)", value)
+			}
+			lsArgs.SniffLimit = n
+		}
+	default:
+		return fmt.Errorf("unrecognized option '--%s'", name)
+	}
+	return nil
+}
+
+// ─────────────────────────────────────────────
+// File-type detection
+// ─────────────────────────────────────────────
+
+// getFileType classifies an entry. info must come from Lstat (as
+// DirEntry.Info does) so symbolic links are seen as links; no further
+// syscall is made for them.
+func getFileType(info fs.FileInfo, path string) FileType {
+	// Symbolic link check MUST come before directory check because a symlink
+	// to a directory would otherwise report as a directory.
+	if info.Mode()&os.ModeSymlink != 0 {
+		return FileTypeSymbolicLink
+	}
+
+	if info.IsDir() {
+		return FileTypeDirectory
+	}
+
+	// User rules for exact names and globs override everything below.
+	if ft, ok := classifyByName(info.Name()); ok {
+		return ft
+	}
+
+	if checkExecutable(info) {
+		return FileTypeExecutable
+	}
+
+	ext := strings.ToLower(filepath.Ext(info.Name()))
+
+	if ft, ok := userExtTypes[ext]; ok {
+		return ft
+	}
+
+	// O(1) map lookup instead of iterating three slices.
+	extFT, extKnown := extTypeMap[ext]
+	if extKnown && extFT == FileTypeExecutable && !detectExecutableByExtension {
+		extFT = FileTypeOther
+	}
+
+	// With --sniff the file's leading bytes beat its extension.
+	if ft, ok := sniffFileType(info, path, extFT, extKnown); ok {
+		return ft
+	}
+
+	if extKnown {
+		return extFT
+	}
+	return FileTypeOther
+}
+
+// ─────────────────────────────────────────────
+// Filtering
+// ─────────────────────────────────────────────
+
+func passesFilter(info fs.FileInfo, path string, fileType FileType, args *Options) bool {
+	name := info.Name()
+
+	if args.SearchTerm != "" {
+		if args.IgnoreCase {
+			if !strings.Contains(strings.ToLower(name), strings.ToLower(args.SearchTerm)) {
+				return false
+			}
+		} else if args.StrictCase {
+			if !strings.Contains(name, args.SearchTerm) {
+				return false
+			}
+		}
+	}
+
+	if args.FilterType != "" {
+		if typeIndicators[fileType] != args.FilterType {
+			return false
+		}
+	}
+
+	if args.MIMEPrefix != "" {
+		if !strings.HasPrefix(fileMIME(info, path), args.MIMEPrefix) {
+			return false
+		}
+	}
+
+	if args.Arch != "" {
+		if b := binaryInfo(info, path); b == nil || !b.Matches(args.Arch) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isIgnored reports whether a directory entry is hidden from the listing,
+// either as a dot file without -a or by an --ignore pattern.
+func isIgnored(name string, args *Options) bool {
+	if !args.ShowAll && strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, pattern := range args.Ignore {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ─────────────────────────────────────────────
+// Sorting
+// ─────────────────────────────────────────────
+
+var validSortKeys = []string{"name", "size", "time", "extension", "none"}
+
+// sortItems orders items by args.Sort. Names break ties for the other keys;
+// foldCase selects case-insensitive name ordering.
+func sortItems(items []Entry, args *Options, foldCase bool) {
+	if args.Sort == "none" {
+		return
+	}
+	nameLess := func(a, b string) bool {
+		if foldCase {
+			return strings.ToLower(a) < strings.ToLower(b)
+		}
+		return a < b
+	}
+	less := func(a, b Entry) bool {
+		switch args.Sort {
+		case "size":
+			if a.Size() != b.Size() {
+				return a.Size() > b.Size()
+			}
+		case "time":
+			if !a.ModTime().Equal(b.ModTime()) {
+				return a.ModTime().After(b.ModTime())
+			}
+		case "extension":
+			ea := strings.ToLower(filepath.Ext(a.Name()))
+			eb := strings.ToLower(filepath.Ext(b.Name()))
+			if ea != eb {
+				return ea < eb
+			}
+		}
+		return nameLess(a.Name(), b.Name())
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if args.Reverse {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+}
+
+// ─────────────────────────────────────────────
+// Tree-entry formatting helper (DRY)
+// ─────────────────────────────────────────────
+
+// formatTreeEntry computes the display name for a single directory entry in
+// tree mode and reports whether the entry should be skipped.
+func formatTreeEntry(item Entry, args *Options) (displayName string, isDir bool, skip bool) {
+	info, fullPath := item.FileInfo, item.Path
+
+	isDir = info.IsDir()
+	name := info.Name()
+	fileType := getFileType(info, fullPath)
+
+	if !passesFilter(info, fullPath, fileType, args) {
+		return "", isDir, true
+	}
+
+	if args.ShowFileType {
+		name += typeIndicators[fileType]
+	}
+
+	if colorEnabled && args.SetColor {
+		displayName = fileColor(info, fullPath, fileType) + name + ansiReset
+	} else {
+		displayName = name
+	}
+	if args.Context {
+		displayName = selinuxContext(fullPath) + " " + displayName
+	}
+	return displayName, isDir, false
+}
+
+// ─────────────────────────────────────────────
+// Tree display (unified – no duplicate printing)
+// ─────────────────────────────────────────────
+
+var treeDepthColors = []string{
+	"\033[2;33m", // dim yellow
+	"\033[2;36m", // dim cyan
+	"\033[2;32m", // dim green
+	"\033[2;35m", // dim magenta
+	"\033[2;34m", // dim blue
+	"\033[2;91m", // dim bright red
+}
+
+// displayTreeRoot prints root and, when it passes the filter, the tree
+// below it.
+func displayTreeRoot(ctx context.Context, scan *scanState, root Entry, args *Options) {
+	// root.FileInfo follows a symlinked root; its type comes from Lstat.
+	rootInfo := root.FileInfo
+	if _, _, inArchive := archiveFor(root.Path); !inArchive {
+		if li, err := lstatPath(root.Path); err == nil {
+			rootInfo = li
+		}
+	}
+	rootType := getFileType(rootInfo, root.Path)
+
+	// Always print the root directory header.
+	var rootDisplay string
+	if colorEnabled && args.SetColor {
+		rootDisplay = fileColor(root.FileInfo, root.Path, rootType) + root.Name() + ansiReset
+	} else {
+		rootDisplay = root.Name()
+	}
+	if args.ShowFileType {
+		rootDisplay += typeIndicators[rootType]
+	}
+	scan.printf("%s\n", rootDisplay)
+
+	// Only recurse into it when it passes the filter (or there is no
+	// filter, meaning all roots are valid).
+	if passesFilter(root.FileInfo, root.Path, rootType, args) {
+		displayTree(ctx, scan, root.Path, args, "", 0)
+	}
+}
+
+// displayTree prints the directory tree rooted at path in the style of the
+// standard `tree` command.  The root is printed by displayTreeRoot.
+// Each node is printed exactly once: by its parent when iterating children.
+func displayTree(ctx context.Context, scan *scanState, path string, args *Options, prefix string, depth int) {
+	if ctx.Err() != nil || !scan.enterDir(path) {
+		return
+	}
+	visible, err := readDirEntries(path, args)
+	if err != nil {
+		problems.add("cannot read directory", path, err)
+		return
+	}
+
+	sortItems(visible, args, true)
+
+	canColor := colorEnabled
+	colorize := func(s string) string {
+		if canColor {
+			return treeDepthColors[depth%len(treeDepthColors)] + s + ansiReset
+		}
+		return s
+	}
+
+	// Type detection and filtering may read files; do it in parallel and
+	// drop filtered entries before drawing so the last connector is right.
+	type treeLine struct {
+		item        Entry
+		displayName string
+		isDir, skip bool
+	}
+	lines := make([]treeLine, len(visible))
+	parallelEach(len(visible), func(i int) {
+		l := &lines[i]
+		l.item = visible[i]
+		l.displayName, l.isDir, l.skip = formatTreeEntry(visible[i], args)
+	})
+	shown := lines[:0]
+	for _, l := range lines {
+		if !l.skip {
+			shown = append(shown, l)
+		}
+	}
+	// Everything read from this directory's files is in lines now.
+	dropFileCaches()
+	dropDirBudgets(path)
+
+	for i, l := range shown {
+		if ctx.Err() != nil || !scan.take() {
+			return
+		}
+		item, displayName, isDir := l.item, l.displayName, l.isDir
+		isLast := i == len(shown)-1
+		connector := colorize("├── ")
+		newPrefix := prefix + colorize("│") + "   "
+		if isLast {
+			connector = colorize("╰── ")
+			newPrefix = prefix + "    "
+		}
+
+		scan.printf("%s%s%s\n", prefix, connector, displayName)
+		if args.XAttr {
+			for _, line := range xattrLines(item.Path) {
+				scan.printf("%s  %s\n", newPrefix, line)
+			}
+		}
+
+		if isDir {
+			displayTree(ctx, scan, item.Path, args, newPrefix, depth+1)
+		}
+	}
+}
+
+// ─────────────────────────────────────────────
+// Layout / column calculation
+// ─────────────────────────────────────────────
+
+func calculateLayout(displayWidths []int, windowWidth int) (rows, cols int, colWidths []int) {
+	if len(displayWidths) == 0 {
+		return 0, 0, nil
+	}
+
+	padding := spaceLength
+	cols = 1
+	colWidths = []int{maxIntSlice(displayWidths)}
+
+	calcWidth := func(widths []int, pad, ncols int) (int, []int) {
+		maxWidths := make([]int, ncols)
+		perLine := len(widths) / ncols
+		if len(widths)%ncols != 0 {
+			perLine++
+		}
+		for col := 0; col < ncols; col++ {
+			start := col * perLine
+			end := minInt(start+perLine, len(widths))
+			for j := start; j < end; j++ {
+				if widths[j] > maxWidths[col] {
+					maxWidths[col] = widths[j]
+				}
+			}
+		}
+		sum := 0
+		for _, w := range maxWidths {
+			sum += w
+		}
+		return sum + (ncols-1)*pad, maxWidths
+	}
+
+	for {
+		nextCols := cols + 1
+		if nextCols > len(displayWidths) {
+			break
+		}
+		tmpWidth, tmpColWidths := calcWidth(displayWidths, padding, nextCols)
+		if tmpWidth > windowWidth {
+			break
+		}
+		colWidths = tmpColWidths
+		cols = nextCols
+	}
+
+	rows = len(displayWidths) / cols
+	if len(displayWidths)%cols != 0 {
+		rows++
+	}
+	return rows, cols, colWidths
+}
+
+func maxIntSlice(nums []int) int {
+	if len(nums) == 0 {
+		return 0
+	}
+	m := nums[0]
+	for _, n := range nums[1:] {
+		if n > m {
+			m = n
+		}
+	}
+	return m
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ─────────────────────────────────────────────
+// File metadata helpers
+// ─────────────────────────────────────────────
+
+func formatRelativeTime(t time.Time) string {
+	now := currentTime()
+	d := now.Sub(t)
+	if d < 0 {
+		d = -d
+	}
+
+	seconds := int(d.Seconds())
+	minutes := int(d.Minutes())
+	hours := int(d.Hours())
+	days := hours / 24
+	weeks := days / 7
+	months := days / 30
+	years := days / 365
+
+	switch {
+	case seconds < 5:
+		return "now"
+	case seconds < 60:
+		return fmt.Sprintf("%d secs ago", seconds)
+	case minutes == 1:
+		return "a minute ago"
+	case minutes < 60:
+		return fmt.Sprintf("%d minutes ago", minutes)
+	case hours == 1:
+		return "an hour ago"
+	case hours < 24:
+		return fmt.Sprintf("%d hours ago", hours)
+	case days == 1:
+		return "a day ago"
+	case days < 7:
+		return fmt.Sprintf("%d days ago", days)
+	case weeks == 1:
+		return "a week ago"
+	case weeks < 4:
+		return fmt.Sprintf("%d weeks ago", weeks)
+	case months == 1:
+		return "a month ago"
+	case months < 12:
+		return fmt.Sprintf("%d months ago", months)
+	case years == 1:
+		return "a year ago"
+	default:
+		return fmt.Sprintf("%d years ago", years)
+	}
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// ─────────────────────────────────────────────
+// Display: flat / column layout
+// ─────────────────────────────────────────────
+
+// itemDisplayName renders an entry for the flat layouts: colored name,
+// type indicator and -Z context. width excludes the color codes.
+func itemDisplayName(item Entry, args *Options) (display string, width int) {
+	fileType := getFileType(item.FileInfo, item.Path)
+	baseName := item.Name()
+
+	if args.ShowFileType {
+		baseName += typeIndicators[fileType]
+	}
+
+	if colorEnabled && args.SetColor {
+		display = fileColor(item.FileInfo, item.Path, fileType) + baseName + ansiReset
+	} else {
+		display = baseName
+	}
+	if args.Context {
+		ctx := selinuxContext(item.Path) + " "
+		display = ctx + display
+		baseName = ctx + baseName
+	}
+	return display, getStringDisplayWidth(baseName)
+}
+
+func displayItems(w io.Writer, items []Entry, args *Options) {
+	if len(items) == 0 {
+		fmt.Fprintln(w, "No matching files found")
+		return
+	}
+
+	displayNames := make([]string, len(items))
+	displayWidths := make([]int, len(items))
+	var xattrs [][]string
+	if args.XAttr {
+		xattrs = make([][]string, len(items))
+	}
+
+	for i, item := range items {
+		displayNames[i], displayWidths[i] = itemDisplayName(item, args)
+		if xattrs != nil {
+			xattrs[i] = xattrLines(item.Path)
+		}
+	}
+
+	// With --xattr each entry gets its own line so the attributes can
+	// follow it.
+	if xattrs != nil {
+		for i, name := range displayNames {
+			fmt.Fprintln(w, name)
+			for _, line := range xattrs[i] {
+				fmt.Fprintln(w, "    "+line)
+			}
+		}
+		return
+	}
+
+	windowWidth := getTerminalWidth()
+	rows, _, colWidths := calculateLayout(displayWidths, windowWidth)
+
+	lines := make([][]string, rows)
+
+	for idx, name := range displayNames {
+		row := idx % rows
+		col := idx / rows
+		if col >= len(colWidths) {
+			continue
+		}
+		padding := colWidths[col] - displayWidths[idx]
+		if padding > 0 {
+			name += strings.Repeat(" ", padding)
+		}
+		lines[row] = append(lines[row], name)
+	}
+
+	space := strings.Repeat(" ", spaceLength)
+	for _, line := range lines {
+		fmt.Fprintln(w, strings.Join(line, space))
+	}
+}
+
+func colorizeModeString(mode string) string {
+	if !colorEnabled {
+		return mode
+	}
+	var b strings.Builder
+	for _, ch := range mode {
+		if seq := modeColors[ch]; seq != "" {
+			b.WriteString(seq)
+			b.WriteRune(ch)
+			b.WriteString(ansiReset)
+		} else {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+// ─────────────────────────────────────────────
+// Display: long / table format
+// ─────────────────────────────────────────────
+
+// longRow is one entry of the -l table together with its resolved type.
+type longRow struct {
+	Entry
+	fileType FileType
+
+	interp     string
+	interpRead bool
+
+	text     *TextStats
+	textRead bool
+}
+
+// interpreter returns the shebang interpreter of a regular file, reading
+// the file at most once per row.
+func (r *longRow) interpreter() string {
+	if !r.interpRead {
+		r.interpRead = true
+		if r.Mode().IsRegular() {
+			r.interp, _ = scriptInterpreter(r.FileInfo, r.Path)
+		}
+	}
+	return r.interp
+}
+
+// textStats returns the text statistics of the row, reading the file at
+// most once for the lines, encoding and eol columns together.
+func (r *longRow) textStats() *TextStats {
+	if !r.textRead {
+		r.textRead = true
+		r.text = textStats(r.FileInfo, r.Path)
+	}
+	return r.text
+}
+
+// scriptNote is the name-column annotation for scripts. warn is set when a
+// script with a shebang lacks the executable bit.
+func (r *longRow) scriptNote() (note string, warn bool) {
+	interp := r.interpreter()
+	if interp == "" {
+		return "", false
+	}
+	if hasExecBit && !checkExecutable(r.FileInfo) {
+		return " (script: " + interp + ", missing +x)", true
+	}
+	return " (script: " + interp + ")", false
+}
+
+// longColumn describes a column of the -l table. value returns the plain
+// cell text; paint, when set, colors the unpadded cell.
+type longColumn struct {
+	header     string
+	minWidth   int
+	alignRight bool
+	value      func(r *longRow, args *Options) string
+	paint      func(r *longRow, cell string) string
+}
+
+// longColumns is the registry of columns selectable with --columns. The
+// row index column is always shown first and is not part of it.
+var longColumns = map[string]longColumn{
+	"name": {
+		header: "name",
+		value: func(r *longRow, args *Options) string {
+			bn := r.Name()
+			if r.fileType == FileTypeSymbolicLink {
+				if target, err := readLink(r.Path); err == nil {
+					bn += " -> " + target
+				}
+			}
+			if args.ShowFileType {
+				bn += typeIndicators[r.fileType]
+			}
+			note, _ := r.scriptNote()
+			return bn + note
+		},
+		paint: func(r *longRow, cell string) string {
+			note, warn := r.scriptNote()
+			name := fileColor(r.FileInfo, r.Path, r.fileType) + strings.TrimSuffix(cell, note) + ansiReset
+			if warn && tableWarningColor != "" {
+				return name + tableWarningColor + note + ansiReset
+			}
+			return name + note
+		},
+	},
+	"mode": {
+		header: "mode",
+		value:  func(r *longRow, _ *Options) string { return lsModeString(r.Mode()) + modeMarkers(r.Path) },
+		paint:  func(_ *longRow, cell string) string { return colorizeModeString(cell) },
+	},
+	"links": {
+		header:     "links",
+		minWidth:   9,
+		alignRight: true,
+		value:      func(r *longRow, _ *Options) string { return strconv.FormatUint(r.Links, 10) },
+	},
+	"user": {
+		header: "user",
+		value:  func(r *longRow, _ *Options) string { return r.OwnerName },
+	},
+	"group": {
+		header: "group",
+		value:  func(r *longRow, _ *Options) string { return r.GroupName },
+	},
+	"size": {
+		header:     "size",
+		alignRight: true,
+		value:      func(r *longRow, _ *Options) string { return formatSize(r.Size()) },
+	},
+	"modified": {
+		header: "modified",
+		value:  func(r *longRow, _ *Options) string { return formatRelativeTime(r.ModTime()) },
+	},
+	"mime": {
+		header: "mime",
+		value:  func(r *longRow, _ *Options) string { return fileMIME(r.FileInfo, r.Path) },
+	},
+	"arch": {
+		header: "arch",
+		value: func(r *longRow, _ *Options) string {
+			if b := binaryInfo(r.FileInfo, r.Path); b != nil {
+				return b.String()
+			}
+			return ""
+		},
+	},
+	"archive": {
+		header: "archive",
+		value: func(r *longRow, _ *Options) string {
+			if r.fileType != FileTypeArchive || !r.Mode().IsRegular() {
+				return ""
+			}
+			return archiveSummary(r.Path)
+		},
+	},
+	"lines": {
+		header:     "lines",
+		alignRight: true,
+		value: func(r *longRow, _ *Options) string {
+			if t := r.textStats(); t != nil && t.Encoding != "binary" {
+				return strconv.Itoa(t.Lines)
+			}
+			return ""
+		},
+	},
+	"encoding": {
+		header: "encoding",
+		value: func(r *longRow, _ *Options) string {
+			if t := r.textStats(); t != nil {
+				return t.Encoding
+			}
+			return ""
+		},
+	},
+	"eol": {
+		header: "eol",
+		value: func(r *longRow, _ *Options) string {
+			if t := r.textStats(); t != nil {
+				return t.EOL
+			}
+			return ""
+		},
+		paint: func(_ *longRow, cell string) string {
+			if cell == "mixed" && tableWarningColor != "" {
+				return tableWarningColor + cell + ansiReset
+			}
+			return cell
+		},
+	},
+	"context": {
+		header: "context",
+		value:  func(r *longRow, _ *Options) string { return selinuxContext(r.Path) },
+	},
+	"attrs": {
+		header: "attrs",
+		value: func(r *longRow, _ *Options) string {
+			attrs, _ := inodeAttrs(r.FileInfo, r.Path)
+			return attrs
+		},
+		paint: func(r *longRow, cell string) string {
+			if _, blocking := inodeAttrs(r.FileInfo, r.Path); blocking && tableWarningColor != "" {
+				return tableWarningColor + cell + ansiReset
+			}
+			return cell
+		},
+	},
+	"access": {
+		header: "access",
+		value:  func(r *longRow, _ *Options) string { return effectiveAccess(r.FileInfo, r.Path) },
+		paint: func(_ *longRow, cell string) string {
+			if cell == "none" && tableWarningColor != "" {
+				return tableWarningColor + cell + ansiReset
+			}
+			return cell
+		},
+	},
+	"octal": {
+		header: "octal",
+		value:  func(r *longRow, _ *Options) string { return octalMode(r.Mode()) },
+	},
+	"caps": {
+		header: "caps",
+		value:  func(r *longRow, _ *Options) string { return fileCapabilities(r.Path) },
+	},
+	"media": {
+		header: "media",
+		value: func(r *longRow, _ *Options) string {
+			if r.fileType != FileTypeMedia {
+				return ""
+			}
+			if m := mediaInfo(r.FileInfo, r.Path); m != nil {
+				return m.String()
+			}
+			return ""
+		},
+	},
+}
+
+// defaultLongColumns returns the columns shown when --columns is not given.
+// The links column is meaningless on Windows.
+func defaultLongColumns() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"name", "mode", "user", "group", "size", "modified"}
+	}
+	return []string{"name", "mode", "links", "user", "group", "size", "modified"}
+}
+
+func parseColumnList(value string) ([]string, error) {
+	var cols []string
+	for _, c := range strings.Split(value, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if _, ok := longColumns[c]; !ok {
+			names := make([]string, 0, len(longColumns))
+			for name := range longColumns {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown column %q (valid: %s)", c, strings.Join(names, ", "))
+		}
+		cols = append(cols, c)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("option '--columns' requires at least one column")
+	}
+	return cols, nil
+}
+
+// longColumnsFor returns the columns of the -l table: --columns or the
+// defaults, plus the context column for -Z.
+func longColumnsFor(args *Options) []longColumn {
+	keys := args.Columns
+	if len(keys) == 0 {
+		keys = defaultLongColumns()
+	}
+	// -Z adds the context column after the mode, like ls -lZ.
+	if args.Context && !containsString(keys, "context") {
+		at := len(keys)
+		for i, k := range keys {
+			if k == "mode" {
+				at = i + 1
+			}
+		}
+		keys = append(append(append([]string(nil), keys[:at]...), "context"), keys[at:]...)
+	}
+	columns := make([]longColumn, len(keys))
+	for i, k := range keys {
+		columns[i] = longColumns[k]
+	}
+	return columns
+}
+
+// buildLongRows computes the cells of every row, and with --xattr the
+// lines shown under each. Columns such as mime or lines read the files,
+// so rows are filled in parallel.
+func buildLongRows(items []Entry, columns []longColumn, args *Options) (rows []longRow, cells, extras [][]string) {
+	rows = make([]longRow, len(items))
+	cells = make([][]string, len(items))
+	extras = make([][]string, len(items))
+	parallelEach(len(items), func(i int) {
+		item := items[i]
+		rows[i] = longRow{Entry: item, fileType: getFileType(item.FileInfo, item.Path)}
+		cells[i] = make([]string, len(columns))
+		for c, col := range columns {
+			cells[i][c] = col.value(&rows[i], args)
+		}
+		// --xattr lines go under the entry, in the first column.
+		if args.XAttr {
+			for _, line := range xattrLines(item.Path) {
+				extras[i] = append(extras[i], "  "+line)
+			}
+		}
+	})
+	return rows, cells, extras
+}
+
+func displayLongFormat(w io.Writer, items []Entry, args *Options) {
+	if len(items) == 0 {
+		fmt.Fprintln(w, "No matching files found")
+		return
+	}
+
+	columns := longColumnsFor(args)
+
+	// Width of the row index column.
+	idxWidth := 1
+	idxStr := strconv.Itoa(len(items) - 1)
+	if w := len(idxStr); w > idxWidth {
+		idxWidth = w
+	}
+
+	widths := make([]int, len(columns))
+	for c, col := range columns {
+		widths[c] = maxInt(col.minWidth, getStringDisplayWidth(col.header))
+	}
+
+	// Pre-compute formatted values to avoid duplicate calls.
+	rows, cells, extras := buildLongRows(items, columns, args)
+	for i := range rows {
+		for c := range columns {
+			if w := getStringDisplayWidth(cells[i][c]); w > widths[c] {
+				widths[c] = w
+			}
+		}
+		for _, line := range extras[i] {
+			if w := getStringDisplayWidth(line); w > widths[0] {
+				widths[0] = w
+			}
+		}
+	}
+
+	// Nushell-style cell padding: 1 space on each side.
+	const pad = 2
+	idxWidth += pad
+	for c := range widths {
+		widths[c] += pad
+	}
+
+	// Helper to build a border row.
+	border := func(left, mid, right, h string) string {
+		parts := []string{strings.Repeat(h, idxWidth)}
+		for _, w := range widths {
+			parts = append(parts, strings.Repeat(h, w))
+		}
+		return left + strings.Join(parts, mid) + right
+	}
+
+	// Border and header colors come from the theme.
+	useColor := colorEnabled && args.SetColor
+	paintBorder := func(s string) string {
+		if !useColor || tableBorderColor == "" {
+			return s
+		}
+		return tableBorderColor + s + ansiReset
+	}
+	vbar := paintBorder("│")
+
+	topLine := paintBorder(border("╭", "┬", "╮", "─"))
+	divider := paintBorder(border("├", "┼", "┤", "─"))
+	bottomLine := paintBorder(border("╰", "┴", "╯", "─"))
+
+	// Header row — lowercase, centered, in the theme's header color.
+	headerGreen := ""
+	headerReset := ""
+	if useColor && tableHeaderColor != "" {
+		headerGreen = tableHeaderColor
+		headerReset = ansiReset
+	}
+
+	headerFields := []string{centerByWidth("#", idxWidth)}
+	for c, col := range columns {
+		headerFields = append(headerFields, centerByWidth(col.header, widths[c]))
+	}
+	header := vbar + headerGreen + strings.Join(headerFields, headerReset+vbar+headerGreen) + headerReset + vbar
+
+	fmt.Fprintln(w, topLine)
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, divider)
+
+	for i := range rows {
+		fields := []string{" " + padLeftByWidth(strconv.Itoa(i), idxWidth-pad) + " "}
+		for c, col := range columns {
+			cell := cells[i][c]
+			padding := strings.Repeat(" ", maxInt(0, widths[c]-pad-getStringDisplayWidth(cell)))
+			if useColor && col.paint != nil && cell != "" {
+				cell = col.paint(&rows[i], cell)
+			}
+			if col.alignRight {
+				fields = append(fields, " "+padding+cell+" ")
+			} else {
+				fields = append(fields, " "+cell+padding+" ")
+			}
+		}
+		fmt.Fprintln(w, vbar+strings.Join(fields, vbar)+vbar)
+
+		for _, line := range extras[i] {
+			fields := []string{strings.Repeat(" ", idxWidth)}
+			for c := range columns {
+				cell := ""
+				if c == 0 {
+					cell = line
+				}
+				padding := strings.Repeat(" ", maxInt(0, widths[c]-pad-getStringDisplayWidth(cell)))
+				fields = append(fields, " "+cell+padding+" ")
+			}
+			fmt.Fprintln(w, vbar+strings.Join(fields, vbar)+vbar)
+		}
+	}
+
+	fmt.Fprintln(w, bottomLine)
+}
@@ -0,0 +1,73 @@
+package enls
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseArgsErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string // substring of the error
+	}{
+		{[]string{"-x"}, "invalid option -- 'x'"},
+		{[]string{"-lq"}, "invalid option -- 'q'"},
+		{[]string{"-"}, "invalid option: -"},
+		{[]string{"-sS"}, "mutually exclusive"},
+		{[]string{"--bogus"}, "unrecognized option '--bogus'"},
+		{[]string{"--color=sometimes"}, "--color"},
+		{[]string{"--tty=maybe"}, "--tty"},
+		{[]string{"--now=yesterday"}, "--now"},
+		{[]string{"--width=0"}, "--width"},
+		{[]string{"--width=wide"}, "--width"},
+		{[]string{"--ambiguous-width=3"}, "--ambiguous-width"},
+		{[]string{"--theme="}, "'--theme' requires an argument"},
+		{[]string{"--sort=colour"}, "--sort"},
+		{[]string{"--columns=name,bogus"}, `unknown column "bogus"`},
+		{[]string{"--ignore=["}, "invalid --ignore pattern"},
+		{[]string{"--max-entries=-1"}, "--max-entries"},
+		{[]string{"--timeout=soon"}, "--timeout"},
+		{[]string{"--timeout=0"}, "--timeout"},
+		{[]string{"--mime="}, "'--mime' requires"},
+		{[]string{"--arch="}, "'--arch' requires"},
+		{[]string{"--sniff=0"}, "--sniff"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			_, err := ParseArgs(tt.args)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		check func(*Options) bool
+	}{
+		{"path", []string{"-l", "/tmp"}, func(o *Options) bool { return o.LongFormat && o.Path == "/tmp" }},
+		{"search term", []string{"-s", "readme"}, func(o *Options) bool { return o.IgnoreCase && o.SearchTerm == "readme" }},
+		{"value as next arg", []string{"--sort", "size"}, func(o *Options) bool { return o.Sort == "size" }},
+		{"timeout in seconds", []string{"--timeout=1.5"}, func(o *Options) bool { return o.Timeout == 1500*time.Millisecond }},
+		{"sniff limit", []string{"--sniff=20"}, func(o *Options) bool { return o.Sniff && o.SniffLimit == 20 }},
+		{"help stops parsing", []string{"-h", "--bogus"}, func(o *Options) bool { return o.ShowHelp }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(opts) {
+				t.Errorf("unexpected options: %+v", opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package enls
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ─────────────────────────────────────────────
+// Filesystem backend
+// ─────────────────────────────────────────────
+//
+// Listing reads through fsys. The default is the local disk, addressed by
+// native paths. Any other fs.FS (embed.FS, zip.Reader, fstest.MapFS,
+// os.DirFS, an overlay) is addressed by slash-separated fs.ValidPath names
+// and offers what it implements: fs.ReadLinkFS for symbolic links and
+// OwnerFS for owners. Disk-only details such as extended attributes,
+// inode flags and access(2) are left blank there.
+
+// OwnerFS is implemented by file systems that know who owns an entry.
+type OwnerFS interface {
+	fs.FS
+	Owner(name string, info fs.FileInfo) (owner, group string)
+}
+
+// diskFS is the local disk. Unlike os.DirFS it takes native paths,
+// absolute or relative to the working directory.
+type diskFS struct{}
+
+func (diskFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (diskFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (diskFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (diskFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (diskFS) ReadLink(name string) (string, error)       { return os.Readlink(name) }
+
+// fsys is the file system being listed; Lister sets it from Lister.FS.
+var fsys fs.FS = diskFS{}
+
+func useFS(f fs.FS) {
+	if f == nil {
+		f = diskFS{}
+	}
+	fsys = f
+}
+
+// onDisk reports whether the local disk is being listed.
+func onDisk() bool {
+	_, ok := fsys.(diskFS)
+	return ok
+}
+
+func statPath(p string) (fs.FileInfo, error) { return fs.Stat(fsys, p) }
+
+func readDirPath(p string) ([]fs.DirEntry, error) { return fs.ReadDir(fsys, p) }
+
+func openPath(p string) (fs.File, error) { return fsys.Open(p) }
+
+// lstatPath describes p itself; without fs.ReadLinkFS links cannot be
+// told apart from their targets.
+func lstatPath(p string) (fs.FileInfo, error) { return fs.Lstat(fsys, p) }
+
+func readLinkPath(p string) (string, error) { return fs.ReadLink(fsys, p) }
+
+// entryOwner returns the owner and group of p, or blanks when the file
+// system cannot say.
+func entryOwner(p string, info fs.FileInfo) (string, string) {
+	if ofs, ok := fsys.(OwnerFS); ok {
+		return ofs.Owner(p, info)
+	}
+	if !onDisk() && info.Sys() == nil {
+		return "", ""
+	}
+	return getFileOwnerGroup(info)
+}
+
+// Path helpers: native on disk, slash-separated elsewhere.
+
+func joinPath(elem ...string) string {
+	if onDisk() {
+		return filepath.Join(elem...)
+	}
+	return path.Join(elem...)
+}
+
+func cleanPath(p string) string {
+	if onDisk() {
+		return filepath.Clean(p)
+	}
+	return path.Clean(p)
+}
+
+func parentDir(p string) string {
+	if onDisk() {
+		return filepath.Dir(p)
+	}
+	return path.Dir(p)
+}
+
+func baseName(p string) string {
+	if onDisk() {
+		return filepath.Base(p)
+	}
+	return path.Base(p)
+}
+
+func pathSeparator() string {
+	if onDisk() {
+		return string(filepath.Separator)
+	}
+	return "/"
+}
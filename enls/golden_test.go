@@ -0,0 +1,104 @@
+package enls
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden")
+
+// goldenNow is the --now every golden listing is rendered at.
+const goldenNow = "2024-06-01T12:00:00Z"
+
+// goldenFS is a small project tree with a mix of file types and ages.
+func goldenFS() fstest.MapFS {
+	now, _ := time.Parse(time.RFC3339, goldenNow)
+	file := func(data string, mode fs.FileMode, age time.Duration) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(data), Mode: mode, ModTime: now.Add(-age)}
+	}
+	dir := func(age time.Duration) *fstest.MapFile {
+		return &fstest.MapFile{Mode: fs.ModeDir | 0755, ModTime: now.Add(-age)}
+	}
+	return fstest.MapFS{
+		"proj":                   dir(time.Hour),
+		"proj/README.md":         file("# proj\n", 0644, 3*24*time.Hour),
+		"proj/build.sh":          file("#!/bin/sh\nmake\n", 0755, 2*time.Hour),
+		"proj/release.tar.gz":    file("not really gzip", 0644, 40*24*time.Hour),
+		"proj/notes.bak":         file("old notes", 0644, 400*24*time.Hour),
+		"proj/logo.png":          file("\x89PNG\r\n\x1a\n", 0644, 5*time.Minute),
+		"proj/src":               dir(30 * time.Minute),
+		"proj/src/main.go":       file("package main\n", 0644, 30*time.Minute),
+		"proj/src/util.go":       file("package main\n\nfunc f() {}\n", 0644, 45*time.Second),
+		"proj/src/internal":      dir(time.Hour),
+		"proj/src/internal/x.go": file("package internal\n", 0644, time.Hour),
+		"proj/docs":              dir(2 * time.Hour),
+		"proj/docs/guide.md":     file("guide\n", 0644, 2*time.Hour),
+
+		"cjk":              dir(time.Hour),
+		"cjk/中文文档.txt":     file("你好\n", 0644, time.Hour),
+		"cjk/日本語ファイル.md":   file("こんにちは\n", 0644, 2*time.Hour),
+		"cjk/한국어.png":      file("x", 0644, 3*time.Hour),
+		"cjk/mixed 混合.tar": file("x", 0644, 4*time.Hour),
+		"cjk/ascii.txt":    file("x", 0644, 5*time.Hour),
+		"cjk/目录":           dir(6 * time.Hour),
+		"cjk/目录/子文件.go":    file("package x\n", 0644, 6*time.Hour),
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"flat", []string{"proj"}},
+		{"flat_types", []string{"-f", "--width=40", "proj"}},
+		{"long", []string{"-l", "proj"}},
+		{"long_sorted", []string{"-l", "--sort=size", "proj"}},
+		{"tree", []string{"-r", "-f", "proj"}},
+		{"tree_sorted", []string{"-r", "--sort=time", "proj/src"}},
+		{"cjk_flat", []string{"--width=40", "cjk"}},
+		{"cjk_long", []string{"-l", "cjk"}},
+		{"cjk_tree", []string{"-r", "cjk"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--now=" + goldenNow, "--width=80", "--tty=no", "--color=never"}, tt.args...)
+			opts, err := ParseArgs(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := NewLister(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.FS = goldenFS()
+			var out, errOut bytes.Buffer
+			l.Stderr = &errOut
+			if status := l.Run(context.Background(), &out); status != ExitOK {
+				t.Fatalf("status %d, stderr:\n%s", status, errOut.String())
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != string(want) {
+				t.Errorf("output differs from %s (run go test -update to accept):\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
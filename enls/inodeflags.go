@@ -0,0 +1,61 @@
+package enls
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// Inode flags (attrs column)
+// ─────────────────────────────────────────────
+
+// inodeFlagNames lists the chattr(1) flags in lsattr(1) order. Extents and
+// inline data are storage details set on nearly every ext4 file and are
+// left out.
+var inodeFlagNames = []struct {
+	bit  uint32
+	name string
+}{
+	{0x00000001, "secure-rm"},    // s
+	{0x00000002, "undelete"},     // u
+	{0x00000008, "sync"},         // S
+	{0x00010000, "dirsync"},      // D
+	{0x00000010, "immutable"},    // i
+	{0x00000020, "append-only"},  // a
+	{0x00000040, "nodump"},       // d
+	{0x00000080, "noatime"},      // A
+	{0x00000004, "compress"},     // c
+	{0x00000800, "encrypted"},    // E
+	{0x00004000, "journal-data"}, // j
+	{0x00001000, "indexed"},      // I
+	{0x00008000, "notail"},       // t
+	{0x00020000, "topdir"},       // T
+	{0x00800000, "nocow"},        // C
+	{0x02000000, "dax"},          // x
+	{0x40000000, "casefold"},     // F
+	{0x20000000, "projinherit"},  // P
+	{0x00100000, "verity"},       // V
+	{0x00000400, "nocompress"},   // m
+}
+
+// inodeFlagsBlocking are the flags that stop even root from modifying a
+// file; the attrs column highlights them.
+const inodeFlagsBlocking = 0x00000010 | 0x00000020
+
+// inodeAttrs renders the chattr flags of path, e.g. "immutable,nodump".
+func inodeAttrs(info fs.FileInfo, path string) (attrs string, blocking bool) {
+	if !onDisk() {
+		return "", false
+	}
+	flags, ok := getInodeFlags(path, info)
+	if !ok {
+		return "", false
+	}
+	var names []string
+	for _, f := range inodeFlagNames {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, ","), flags&inodeFlagsBlocking != 0
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package enls
+
+import (
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+// getInodeFlags returns the chattr(1) flags of a regular file or directory.
+func getInodeFlags(path string, info fs.FileInfo) (flags uint32, ok bool) {
+	if !(info.Mode().IsRegular() || info.IsDir()) {
+		return 0, false
+	}
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, false
+	}
+	defer unix.Close(fd)
+
+	// The kernel reads and writes an int despite the "long" in the name.
+	flags, err = unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return 0, false
+	}
+	return flags, true
+}
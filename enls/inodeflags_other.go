@@ -0,0 +1,9 @@
+//go:build !linux
+
+package enls
+
+import "io/fs"
+
+// Inode flags are only read on Linux, through FS_IOC_GETFLAGS.
+
+func getInodeFlags(path string, info fs.FileInfo) (uint32, bool) { return 0, false }
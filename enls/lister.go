@@ -0,0 +1,419 @@
+// Package enls classifies, lays out and renders directory listings for
+// the enls command and for programs that embed it.
+package enls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ─────────────────────────────────────────────
+// Lister
+// ─────────────────────────────────────────────
+//
+// Lister is the library entry point; the enls command is a thin wrapper
+// around ParseArgs, NewLister and Run.
+//
+//	opts, _ := enls.ParseArgs([]string{"-l", "--sort=size", "/var/log"})
+//	l, _ := enls.NewLister(opts)
+//	entries, _ := l.List(opts.Path)
+//	l.Render(ctx, w, entries)
+
+// A Renderer writes entries to w. Cancelling ctx stops renderers that
+// walk further than the entries they are given, such as TreeRenderer.
+type Renderer interface {
+	Render(ctx context.Context, w io.Writer, entries []Entry, opts *Options) error
+}
+
+// RendererFunc adapts a function to the Renderer interface.
+type RendererFunc func(ctx context.Context, w io.Writer, entries []Entry, opts *Options) error
+
+func (f RendererFunc) Render(ctx context.Context, w io.Writer, entries []Entry, opts *Options) error {
+	return f(ctx, w, entries, opts)
+}
+
+// flatRenderer adapts a layout that draws only the entries it is given.
+func flatRenderer(draw func(io.Writer, []Entry, *Options)) Renderer {
+	return RendererFunc(func(_ context.Context, w io.Writer, entries []Entry, opts *Options) error {
+		defer lockEntries(entries)()
+		draw(w, entries, opts)
+		return nil
+	})
+}
+
+// The built-in flat renderers: the multi-column grid and the -l table.
+// Like TreeRenderer, they draw with the colors, file system and other
+// settings of the Lister that listed the entries.
+var (
+	GridRenderer = flatRenderer(displayItems)
+	LongRenderer = flatRenderer(displayLongFormat)
+)
+
+// TreeRenderer draws each entry as the root of a tree (-r), walking into
+// directories as it goes. It returns ErrTruncated when --max-entries or
+// --timeout ended the walk early. Unreadable subdirectories do not stop
+// the walk; they are reported by Run or Lister.ReportProblems.
+type TreeRenderer struct {
+	// Stderr receives the progress line and the truncation notice; nil
+	// means os.Stderr.
+	Stderr io.Writer
+}
+
+func (t TreeRenderer) Render(ctx context.Context, w io.Writer, entries []Entry, opts *Options) error {
+	errOut := t.Stderr
+	if errOut == nil {
+		errOut = os.Stderr
+	}
+	defer lockEntries(entries)()
+	scan := startScan(opts, w, errOut)
+	for _, root := range entries {
+		displayTreeRoot(ctx, scan, root, opts)
+	}
+	if scan.finish() {
+		return ErrTruncated
+	}
+	return nil
+}
+
+// ErrTruncated is returned by TreeRenderer when --max-entries or --timeout
+// ended the walk early.
+var ErrTruncated = errors.New("listing truncated")
+
+// Lister lists paths according to Options.
+//
+// Listers may be used from several goroutines, but take turns: colors,
+// the file system being listed and the other settings are package state,
+// which List and Run install for their Lister while holding a package-wide
+// lock, as do the built-in renderers for the Lister whose entries they
+// draw. Renderers themselves run without the lock, so a custom Renderer
+// may call List. Classification rules from Config.ApplyRules are shared by
+// all Listers.
+type Lister struct {
+	Options *Options
+	// FS is the file system to list; nil means the local disk, addressed
+	// by native paths. Other file systems take fs.ValidPath names, and may
+	// implement fs.ReadLinkFS and OwnerFS.
+	FS fs.FS
+	// Renderer draws listings; nil picks TreeRenderer, LongRenderer or
+	// GridRenderer by Options.Recursive and Options.LongFormat.
+	Renderer Renderer
+	// Stderr receives the progress line, truncation notices and the error
+	// summary; nil means os.Stderr.
+	Stderr io.Writer
+
+	colorEnabled bool
+	colorDepth   ColorDepth
+	palette      palette
+	problems     problemLog
+}
+
+// activeMu is held while a Lister lists or renders; active is the Lister
+// whose settings are installed.
+var (
+	activeMu sync.Mutex
+	active   *Lister
+)
+
+// lock installs l's settings and holds activeMu until the returned func
+// is called.
+func (l *Lister) lock() func() {
+	activeMu.Lock()
+	if active != l {
+		// Per-file caches and per-directory budgets are keyed by path,
+		// which means something else on another Lister's file system.
+		dropFileCaches()
+		sniffPerDir = map[string]int{}
+		scriptNotePerDir = map[string]int{}
+		active = l
+	}
+	l.install()
+	return activeMu.Unlock
+}
+
+// lockEntries installs the settings of the Lister that listed entries, and
+// the archive they lie in, until the returned func is called. Entries built
+// by hand are drawn with whatever settings are installed.
+func lockEntries(entries []Entry) func() {
+	var l *Lister
+	var a *archiveIndex
+	for _, e := range entries {
+		if l == nil {
+			l = e.lister
+		}
+		if a == nil {
+			a = e.archive
+		}
+	}
+	unlock := activeMu.Unlock
+	if l != nil {
+		unlock = l.lock()
+	} else {
+		activeMu.Lock()
+	}
+	if a != nil {
+		openArchive = a
+	}
+	return unlock
+}
+
+// install copies l's settings into the package state; activeMu must be
+// held.
+func (l *Lister) install() {
+	opts := l.Options
+	fixedNow = opts.Now
+	outputWidth = opts.Width
+	ttyMode = opts.TTY
+	ambiguousWide = opts.AmbiguousWide
+	numericIDs = opts.NumericIDs
+	sniffEnabled = opts.Sniff
+	sniffLimit = defaultSniffLimit
+	if opts.SniffLimit > 0 {
+		sniffLimit = opts.SniffLimit
+	}
+	colorEnabled = l.colorEnabled
+	colorDepth = l.colorDepth
+	l.palette.install()
+	useFS(l.FS)
+	openArchive = nil
+	problems = &l.problems
+}
+
+// NewLister resolves the color, theme, sniffing and output settings of
+// opts and leaves them installed, for HelpText.
+func NewLister(opts *Options) (*Lister, error) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	active = nil
+
+	l := &Lister{Options: opts, palette: defaultPalette}
+	l.install()
+	colorEnabled = resolveColorMode(opts.ColorMode)
+	colorDepth = opts.ColorDepth
+	if colorDepth == ColorDepthAuto {
+		colorDepth = detectColorDepth()
+	}
+	l.colorEnabled, l.colorDepth = colorEnabled, colorDepth
+
+	applyCategoryColors()
+
+	// An explicitly chosen theme wins over LS_COLORS; otherwise LS_COLORS
+	// refines the built-in colors.
+	if opts.Theme != "" {
+		doc, err := loadTheme(opts.Theme)
+		if err == nil {
+			if err = applyTheme(doc); err != nil {
+				err = fmt.Errorf("%s: %w", opts.Theme, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		loadLSColors(os.Getenv("LS_COLORS"))
+	}
+	l.palette = savePalette()
+	active = l
+	return l, nil
+}
+
+func (l *Lister) stderr() io.Writer {
+	if l.Stderr == nil {
+		return os.Stderr
+	}
+	return l.Stderr
+}
+
+// resolve cleans p and stats it. "release.zip/", "release.zip/docs" and
+// --archive refer to the contents of an archive instead of the file itself;
+// the archive is then installed as openArchive.
+func (l *Lister) resolve(p string) (string, fs.FileInfo, error) {
+	// filepath.Clean already normalises separators on every platform,
+	// including Windows — do NOT do an additional ReplaceAll here as it
+	// would corrupt UNC paths (\\server\share).
+	trailingSlash := strings.HasSuffix(p, "/") || strings.HasSuffix(p, string(filepath.Separator))
+	p = cleanPath(p)
+
+	info, a, err := openArchivePath(p, l.Options.Archive || trailingSlash)
+	openArchive = a
+	if err == errNotArchive {
+		if l.Options.Archive {
+			err = fmt.Errorf("%s: %v", p, err)
+		} else {
+			info, err = statPath(p)
+		}
+	}
+	return p, info, err
+}
+
+// List returns the filtered, sorted entries of a directory, or the path
+// itself when it is not one. With Options.Recursive it returns the path
+// itself, the root for TreeRenderer.
+func (l *Lister) List(p string) ([]Entry, error) {
+	defer l.lock()()
+	p, info, err := l.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	return l.list(p, info)
+}
+
+// list lists p, which resolve returned with info.
+func (l *Lister) list(p string, info fs.FileInfo) ([]Entry, error) {
+	args := l.Options
+
+	var items []Entry
+	if args.Recursive {
+		return []Entry{{FileInfo: info, Path: p, lister: l, archive: openArchive}}, nil
+	} else if info.IsDir() {
+		entries, err := readDirEntries(p, args)
+		if err != nil {
+			return nil, err
+		}
+		items = listingItems(entries, args)
+	} else if a, inner, ok := archiveFor(p); ok {
+		// Single archive member.
+		e := a.entries[inner]
+		items = append(items, Entry{
+			FileInfo:  e.info,
+			Path:      p,
+			Links:     1,
+			OwnerName: e.owner,
+			GroupName: e.group,
+		})
+	} else {
+		// Single-file argument.
+		info, err := lstatPath(p)
+		if err != nil {
+			return nil, err
+		}
+		owner, group := entryOwner(p, info)
+		items = append(items, Entry{
+			FileInfo:  info,
+			Path:      p,
+			Links:     getLinkCount(info),
+			OwnerName: owner,
+			GroupName: group,
+		})
+	}
+
+	for i := range items {
+		items[i].lister = l
+		items[i].archive = openArchive
+	}
+	sortItems(items, args, runtime.GOOS == "windows")
+	return items, nil
+}
+
+// Render writes entries with l.Renderer. The Renderer is called without
+// the lock held, so it may call l.List.
+func (l *Lister) Render(ctx context.Context, w io.Writer, entries []Entry) error {
+	r := l.Renderer
+	if r == nil {
+		switch {
+		case l.Options.Recursive:
+			r = TreeRenderer{Stderr: l.stderr()}
+		case l.Options.LongFormat:
+			r = LongRenderer
+		default:
+			r = GridRenderer
+		}
+	}
+	return r.Render(ctx, w, l.claim(entries), l.Options)
+}
+
+// claim attributes entries built by hand to l, so the built-in renderers
+// draw them with l's settings.
+func (l *Lister) claim(entries []Entry) []Entry {
+	owned := slices.Clone(entries)
+	for i := range owned {
+		if owned[i].lister == nil {
+			owned[i].lister = l
+		}
+	}
+	return owned
+}
+
+// Run carries out the listing described by l.Options, the way the enls
+// command does, and returns its exit status. Cancelling ctx stops a walk;
+// Run then returns ExitInterrupted.
+func (l *Lister) Run(ctx context.Context, w io.Writer) int {
+	status := l.run(ctx, w)
+	if ctx.Err() != nil {
+		if l.colorEnabled {
+			fmt.Fprint(w, ansiReset)
+		}
+		status = ExitInterrupted
+	}
+	if l.ReportProblems(l.stderr()) > 0 && status == ExitOK {
+		status = ExitMinor
+	}
+	return status
+}
+
+func (l *Lister) run(ctx context.Context, w io.Writer) int {
+	items, status, ok := l.runList(ctx, w)
+	if !ok || ctx.Err() != nil {
+		return status
+	}
+	if err := l.Render(ctx, w, items); err == ErrTruncated {
+		return ExitMinor
+	} else if err != nil {
+		fmt.Fprintf(l.stderr(), "Error: %v\n", err)
+		return ExitSerious
+	}
+	return ExitOK
+}
+
+// runList does the part of run that holds the lock. --audit, --explain
+// and --stream write their output directly; otherwise ok reports that
+// items are left for Render.
+func (l *Lister) runList(ctx context.Context, w io.Writer) (items []Entry, status int, ok bool) {
+	defer l.lock()()
+	args := l.Options
+	stderr := l.stderr()
+
+	if args.Audit {
+		return nil, runAudit(ctx, w, stderr, cleanPath(args.Path), args), false
+	}
+
+	if args.ExplainMode {
+		p := args.Path
+		if args.ExplainPath != "" {
+			p = args.ExplainPath
+		}
+		if err := explainMode(w, p); err != nil {
+			fmt.Fprintf(stderr, "Error accessing path: %v\n", err)
+			return nil, ExitSerious, false
+		}
+		return nil, ExitOK, false
+	}
+
+	p, info, err := l.resolve(args.Path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error accessing path: %v\n", err)
+		return nil, ExitSerious, false
+	}
+	if _, _, inArchive := archiveFor(p); args.Stream && !args.Recursive && info.IsDir() && !inArchive {
+		if err := streamDir(ctx, w, p, args); err != nil {
+			fmt.Fprintf(stderr, "Error reading directory: %v\n", err)
+			return nil, ExitSerious, false
+		}
+		return nil, ExitOK, false
+	}
+
+	items, err = l.list(p, info)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading directory: %v\n", err)
+		return nil, ExitSerious, false
+	}
+	return items, ExitOK, true
+}
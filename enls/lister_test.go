@@ -0,0 +1,213 @@
+package enls
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// zipFile builds a zip archive holding files, in order.
+func zipFile(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, name := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("contents of " + name))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// newTestLister parses args and lists fsys with plain, fixed-width output.
+func newTestLister(t *testing.T, fsys fstest.MapFS, args ...string) *Lister {
+	t.Helper()
+	opts, err := ParseArgs(append([]string{"--now=" + goldenNow, "--width=80", "--tty=no", "--color=never"}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLister(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.FS = fsys
+	return l
+}
+
+// List followed by a separate Render must still see the archive the
+// entries came from.
+func TestListThenRenderArchive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/rel.zip": {Data: zipFile(t, "docs/a.md", "docs/b.md", "bin/tool")},
+	}
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"flat", []string{"data/rel.zip/"}, []string{"bin", "docs"}},
+		{"long", []string{"-l", "data/rel.zip/docs"}, []string{"a.md", "b.md"}},
+		{"tree", []string{"-r", "data/rel.zip/"}, []string{"rel.zip", "a.md", "b.md", "tool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLister(t, fsys, tt.args...)
+			entries, err := l.List(l.Options.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := l.Render(context.Background(), &out, entries); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.want {
+				if !strings.Contains(out.String(), name) {
+					t.Errorf("output lacks %q:\n%s", name, out.String())
+				}
+			}
+		})
+	}
+}
+
+// A custom Renderer runs without the lock, so it may list more paths.
+func TestRendererCallsList(t *testing.T) {
+	l := newTestLister(t, goldenFS(), "proj")
+	l.Renderer = RendererFunc(func(ctx context.Context, w io.Writer, entries []Entry, opts *Options) error {
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			children, err := l.List(e.Path)
+			if err != nil {
+				return err
+			}
+			if err := GridRenderer.Render(ctx, w, children, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	done := make(chan int)
+	var out bytes.Buffer
+	go func() { done <- l.Run(context.Background(), &out) }()
+	select {
+	case status := <-done:
+		if status != ExitOK {
+			t.Fatalf("status %d", status)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run deadlocked")
+	}
+	for _, name := range []string{"guide.md", "main.go", "internal"} {
+		if !strings.Contains(out.String(), name) {
+			t.Errorf("output lacks %q:\n%s", name, out.String())
+		}
+	}
+}
+
+// The exported renderers draw with the settings of the Lister that listed
+// the entries, even after another Lister was created or used.
+func TestRendererUsesListerSettings(t *testing.T) {
+	colored := newTestLister(t, goldenFS(), "--color=always", "proj")
+	entries, err := colored.List("proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := newTestLister(t, fstest.MapFS{"other": {Mode: fs.ModeDir | 0755}}, "other")
+	if _, err := plain.List("other"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := GridRenderer.Render(context.Background(), &out, entries, colored.Options); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\033[") {
+		t.Errorf("grid lost the colors of its Lister:\n%q", out.String())
+	}
+	out.Reset()
+	if err := LongRenderer.Render(context.Background(), &out, entries, colored.Options); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "README.md") {
+		t.Errorf("long listing lacks README.md:\n%s", out.String())
+	}
+}
+
+// Access problems are reported by the Lister that met them, not by the
+// next one to run.
+func TestProblemsPerLister(t *testing.T) {
+	broken := newTestLister(t, goldenFS(), "-r", "proj")
+	broken.FS = unreadableDir{goldenFS(), "proj/src"}
+	entries, err := broken.List("proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	if err := broken.Render(context.Background(), &out, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	clean := newTestLister(t, goldenFS(), "-r", "proj")
+	clean.Stderr = &errOut
+	if status := clean.Run(context.Background(), &out); status != ExitOK {
+		t.Errorf("status %d, want %d; stderr:\n%s", status, ExitOK, errOut.String())
+	}
+
+	errOut.Reset()
+	if n := broken.ReportProblems(&errOut); n != 1 || !strings.Contains(errOut.String(), "proj/src") {
+		t.Errorf("reported %d problems, want proj/src:\n%s", n, errOut.String())
+	}
+}
+
+// unreadableDir fails ReadDir for one directory.
+type unreadableDir struct {
+	fstest.MapFS
+	dir string
+}
+
+func (u unreadableDir) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == u.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+	}
+	return u.MapFS.ReadDir(name)
+}
+
+// A tree walk forgets what it read from each directory's files once the
+// directory is drawn.
+func TestTreeDropsFileCaches(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for d := range 5 {
+		for f := range 20 {
+			fsys[fmt.Sprintf("top/d%d/f%d", d, f)] = &fstest.MapFile{Data: []byte("\x7fELF not really"), Mode: 0644}
+		}
+	}
+	l := newTestLister(t, fsys, "-r", "--sniff", "top")
+	var out bytes.Buffer
+	if status := l.Run(context.Background(), &out); status != ExitOK {
+		t.Fatalf("status %d", status)
+	}
+	if got := strings.Count(out.String(), "── f"); got != 100 {
+		t.Errorf("listed %d files, want 100", got)
+	}
+
+	sniffMu.Lock()
+	cached, budgets := len(sniffCache), len(sniffPerDir)
+	sniffMu.Unlock()
+	if cached > 20 || budgets > 1 {
+		t.Errorf("after the walk: %d sniffed files and %d directory budgets cached", cached, budgets)
+	}
+}
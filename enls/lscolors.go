@@ -0,0 +1,169 @@
+package enls
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// LS_COLORS compatibility
+// ─────────────────────────────────────────────
+
+// lsColorSuffix is a "*suffix=SGR" entry from LS_COLORS.
+type lsColorSuffix struct {
+	suffix string // lower-cased, e.g. ".tar.gz" or "~"
+	seq    string
+}
+
+var (
+	// lsColorTypes holds the two-letter LS_COLORS keys (di, ln, or, ...)
+	// mapped to complete escape sequences. It is nil when LS_COLORS is
+	// unset, in which case colorMap is used unchanged.
+	lsColorTypes    map[string]string
+	lsColorSuffixes []lsColorSuffix
+
+	// lsColorFileTypes lists the keys that map directly onto a FileType and
+	// therefore override colorMap.
+	lsColorFileTypes = map[string]FileType{
+		"di": FileTypeDirectory,
+		"ln": FileTypeSymbolicLink,
+		"ex": FileTypeExecutable,
+		"fi": FileTypeOther,
+	}
+)
+
+// loadLSColors parses a dircolors-style LS_COLORS value such as
+// "di=01;34:ln=01;36:*.tar=01;31". Unknown keys are kept but ignored, and
+// malformed entries are skipped so a broken variable never prevents
+// listing.
+func loadLSColors(value string) {
+	if value == "" {
+		return
+	}
+	lsColorTypes = make(map[string]string)
+	lsColorSuffixes = nil
+
+	for _, entry := range strings.Split(value, ":") {
+		key, sgr, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || !isSGRParams(sgr) {
+			continue
+		}
+		seq := ""
+		if sgr != "" && sgr != "0" && sgr != "00" {
+			seq = "\033[" + sgr + "m"
+		}
+
+		if strings.HasPrefix(key, "*") {
+			suffix := strings.ToLower(key[1:])
+			if suffix == "" {
+				continue
+			}
+			lsColorSuffixes = append(lsColorSuffixes, lsColorSuffix{suffix: suffix, seq: seq})
+			continue
+		}
+
+		lsColorTypes[key] = seq
+		if ft, ok := lsColorFileTypes[key]; ok {
+			colorMap[ft] = seq
+		}
+	}
+}
+
+// isSGRParams reports whether s only contains digits and semicolons, which
+// keeps arbitrary bytes from the environment out of the terminal.
+func isSGRParams(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != ';' {
+			return false
+		}
+	}
+	return true
+}
+
+// fileColor returns the escape sequence used for an entry. Without LS_COLORS
+// this is simply colorMap[ft]; with it, special modes (orphan links, pipes,
+// devices, setuid, sticky/other-writable directories) and suffix rules are
+// resolved in the same order GNU ls uses.
+func fileColor(info fs.FileInfo, path string, ft FileType) string {
+	// LS_COLORS knows nothing about categories from the config file.
+	if lsColorTypes == nil || isCustomType(ft) {
+		return colorMap[ft]
+	}
+
+	mode := info.Mode()
+	var keys []string
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		if _, _, inArchive := archiveFor(path); !inArchive {
+			if _, err := statPath(path); err != nil {
+				keys = append(keys, "or")
+			}
+		}
+		keys = append(keys, "ln")
+	case mode.IsDir():
+		otherWritable := mode.Perm()&0002 != 0
+		sticky := mode&fs.ModeSticky != 0
+		if sticky && otherWritable {
+			keys = append(keys, "tw")
+		}
+		if otherWritable {
+			keys = append(keys, "ow")
+		}
+		if sticky {
+			keys = append(keys, "st")
+		}
+		keys = append(keys, "di")
+	case mode&fs.ModeNamedPipe != 0:
+		keys = append(keys, "pi")
+	case mode&fs.ModeSocket != 0:
+		keys = append(keys, "so")
+	case mode&fs.ModeCharDevice != 0:
+		keys = append(keys, "cd")
+	case mode&fs.ModeDevice != 0:
+		keys = append(keys, "bd")
+	default:
+		if mode&fs.ModeSetuid != 0 {
+			keys = append(keys, "su")
+		}
+		if mode&fs.ModeSetgid != 0 {
+			keys = append(keys, "sg")
+		}
+		if ft == FileTypeExecutable {
+			keys = append(keys, "ex")
+		}
+	}
+
+	for _, k := range keys {
+		if seq, ok := lsColorTypes[k]; ok {
+			return seq
+		}
+	}
+
+	// Like GNU ls, suffix rules only apply to plain, non-executable files.
+	if mode.IsRegular() && ft != FileTypeExecutable {
+		if seq, ok := matchLSColorSuffix(info.Name()); ok {
+			return seq
+		}
+	}
+
+	return colorMap[ft]
+}
+
+// matchLSColorSuffix returns the sequence of the longest matching "*suffix"
+// rule; later duplicates of the same suffix win.
+func matchLSColorSuffix(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	best := -1
+	for i, rule := range lsColorSuffixes {
+		if !strings.HasSuffix(lower, rule.suffix) {
+			continue
+		}
+		if best < 0 || len(rule.suffix) >= len(lsColorSuffixes[best].suffix) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+	return lsColorSuffixes[best].seq, true
+}
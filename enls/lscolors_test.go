@@ -0,0 +1,89 @@
+package enls
+
+import "testing"
+
+// keepPalette restores the colors when the test ends.
+func keepPalette(t *testing.T) {
+	saved := savePalette()
+	t.Cleanup(saved.install)
+}
+
+func TestLoadLSColors(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		dir      string            // colorMap[FileTypeDirectory] afterwards
+		types    map[string]string // expected lsColorTypes entries
+		suffixes map[string]string // file name -> matched sequence, "-" for no match
+	}{
+		{
+			name:  "types and suffixes",
+			value: "di=01;34:ln=01;36:or=40;31:*.tar=01;31:*.TAR.GZ=32",
+			dir:   "\033[01;34m",
+			types: map[string]string{"ln": "\033[01;36m", "or": "\033[40;31m"},
+			suffixes: map[string]string{
+				"a.tar":    "\033[01;31m",
+				"a.tar.gz": "\033[32m", // the longest suffix wins
+				"A.TAR":    "\033[01;31m",
+				"a.zip":    "-",
+			},
+		},
+		{
+			name:     "reset codes clear the color",
+			value:    "di=00:*.log=0",
+			dir:      "",
+			suffixes: map[string]string{"x.log": ""},
+		},
+		{
+			name:     "malformed entries are skipped",
+			value:    "di=01;34:ex=\033[31m:garbage:=1:*=31:*.md=1;2;x:*.go=36",
+			dir:      "\033[01;34m",
+			types:    map[string]string{"di": "\033[01;34m"},
+			suffixes: map[string]string{"a.go": "\033[36m", "a.md": "-"},
+		},
+		{
+			name:     "later duplicates win",
+			value:    "*.c=31:*.c=32",
+			dir:      defaultPalette.files[FileTypeDirectory],
+			suffixes: map[string]string{"main.c": "\033[32m"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keepPalette(t)
+			defaultPalette.install()
+			loadLSColors(tt.value)
+
+			if got := colorMap[FileTypeDirectory]; got != tt.dir {
+				t.Errorf("directory color %q, want %q", got, tt.dir)
+			}
+			for key, want := range tt.types {
+				if got, ok := lsColorTypes[key]; !ok || got != want {
+					t.Errorf("lsColorTypes[%q] = %q, %v; want %q", key, got, ok, want)
+				}
+			}
+			if _, ok := lsColorTypes["ex"]; ok && tt.name == "malformed entries are skipped" {
+				t.Error("escape bytes in ex= were accepted")
+			}
+			for name, want := range tt.suffixes {
+				got, ok := matchLSColorSuffix(name)
+				if want == "-" {
+					if ok {
+						t.Errorf("%s matched %q, want no match", name, got)
+					}
+				} else if !ok || got != want {
+					t.Errorf("%s: %q, %v; want %q", name, got, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadLSColorsEmpty(t *testing.T) {
+	keepPalette(t)
+	defaultPalette.install()
+	loadLSColors("")
+	if lsColorTypes != nil {
+		t.Error("an empty LS_COLORS enabled LS_COLORS handling")
+	}
+}
@@ -0,0 +1,540 @@
+package enls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"math"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────
+// Media metadata (media column)
+// ─────────────────────────────────────────────
+
+// mediaReadLimit bounds how much of a container is scanned for headers.
+const mediaReadLimit = 1 << 20
+
+// MediaInfo holds what could be learned from a media file's headers.
+type MediaInfo struct {
+	Width, Height int
+	Duration      time.Duration
+	Bitrate       int64 // bits per second
+}
+
+// String renders e.g. "1920x1080, 1:02:03, 5.1 Mb/s".
+func (m *MediaInfo) String() string {
+	var parts []string
+	if m.Width > 0 && m.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", m.Width, m.Height))
+	}
+	if m.Duration > 0 {
+		parts = append(parts, formatDuration(m.Duration))
+	}
+	if m.Bitrate > 0 {
+		parts = append(parts, formatBitrate(m.Bitrate))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatDuration(d time.Duration) string {
+	secs := int64(d.Round(time.Second) / time.Second)
+	h, m, s := secs/3600, (secs/60)%60, secs%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+func formatBitrate(bps int64) string {
+	switch {
+	case bps >= 1_000_000:
+		return fmt.Sprintf("%.1f Mb/s", float64(bps)/1e6)
+	case bps >= 1000:
+		return fmt.Sprintf("%d kb/s", bps/1000)
+	}
+	return fmt.Sprintf("%d b/s", bps)
+}
+
+// mediaInfo parses the headers of an image, audio or video file. The format
+// is taken from the file's leading bytes, not its extension.
+func mediaInfo(info fs.FileInfo, path string) *MediaInfo {
+	r := readHead(info, path)
+	if r == nil || !r.ok || r.ft != FileTypeMedia {
+		return nil
+	}
+
+	f, err := openPath(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	// Files in fsys other than the disk may not support seeking.
+	rs, seekable := f.(io.ReadSeeker)
+
+	var m *MediaInfo
+	switch r.kind {
+	case "png", "jpeg", "gif":
+		if cfg, _, err := image.DecodeConfig(io.LimitReader(f, mediaReadLimit)); err == nil {
+			m = &MediaInfo{Width: cfg.Width, Height: cfg.Height}
+		}
+	case "bmp":
+		m = bmpInfo(r.head)
+	case "webp":
+		m = webpInfo(r.head)
+	case "wav":
+		if seekable {
+			m = wavInfo(rs, info.Size())
+		}
+	case "flac":
+		m = flacInfo(r.head, info.Size())
+	case "mp3":
+		if seekable {
+			m = mp3Info(rs, info.Size())
+		}
+	case "mp4", "mov", "m4a", "3gp":
+		if seekable {
+			m = mp4Info(rs, info.Size())
+		}
+	case "matroska":
+		m = matroskaInfo(f, info.Size())
+	}
+	if m == nil || (m.Width == 0 && m.Duration == 0 && m.Bitrate == 0) {
+		return nil
+	}
+	return m
+}
+
+// withBitrate fills in an average bitrate from the file size.
+func (m *MediaInfo) withBitrate(size int64) *MediaInfo {
+	if m.Bitrate == 0 && m.Duration > 0 {
+		m.Bitrate = int64(float64(size*8) / m.Duration.Seconds())
+	}
+	return m
+}
+
+// ── Images ───────────────────────────────────
+
+func bmpInfo(head []byte) *MediaInfo {
+	if len(head) < 26 {
+		return nil
+	}
+	hdr := binary.LittleEndian.Uint32(head[14:18])
+	if hdr == 12 { // OS/2 BITMAPCOREHEADER
+		return &MediaInfo{
+			Width:  int(binary.LittleEndian.Uint16(head[18:20])),
+			Height: int(binary.LittleEndian.Uint16(head[20:22])),
+		}
+	}
+	w := int32(binary.LittleEndian.Uint32(head[18:22]))
+	h := int32(binary.LittleEndian.Uint32(head[22:26]))
+	if h < 0 { // top-down bitmap
+		h = -h
+	}
+	return &MediaInfo{Width: int(w), Height: int(h)}
+}
+
+func webpInfo(head []byte) *MediaInfo {
+	if len(head) < 30 {
+		return nil
+	}
+	switch string(head[12:16]) {
+	case "VP8 ":
+		// Key frame: 3-byte tag, start code, then 14-bit dimensions.
+		return &MediaInfo{
+			Width:  int(binary.LittleEndian.Uint16(head[26:28]) & 0x3fff),
+			Height: int(binary.LittleEndian.Uint16(head[28:30]) & 0x3fff),
+		}
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(head[21:25])
+		return &MediaInfo{
+			Width:  int(bits&0x3fff) + 1,
+			Height: int((bits>>14)&0x3fff) + 1,
+		}
+	case "VP8X":
+		return &MediaInfo{
+			Width:  int(uint32(head[24])|uint32(head[25])<<8|uint32(head[26])<<16) + 1,
+			Height: int(uint32(head[27])|uint32(head[28])<<8|uint32(head[29])<<16) + 1,
+		}
+	}
+	return nil
+}
+
+// ── WAV ──────────────────────────────────────
+
+func wavInfo(f io.ReadSeeker, size int64) *MediaInfo {
+	if _, err := f.Seek(12, io.SeekStart); err != nil {
+		return nil
+	}
+	var byteRate uint32
+	var hdr [8]byte
+	for i := 0; i < 64; i++ {
+		if _, err := io.ReadFull(f, hdr[:]); err != nil {
+			return nil
+		}
+		id := string(hdr[:4])
+		n := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		switch id {
+		case "fmt ":
+			var fmtChunk [16]byte
+			if n < 16 {
+				return nil
+			}
+			if _, err := io.ReadFull(f, fmtChunk[:]); err != nil {
+				return nil
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:12])
+			n -= 16
+		case "data":
+			if byteRate == 0 {
+				return nil
+			}
+			if n == 0 || n > size {
+				n = size
+			}
+			return &MediaInfo{
+				Duration: time.Duration(float64(n) / float64(byteRate) * float64(time.Second)),
+				Bitrate:  int64(byteRate) * 8,
+			}
+		}
+		// Chunks are padded to even sizes.
+		if _, err := f.Seek(n+n&1, io.SeekCurrent); err != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ── FLAC ─────────────────────────────────────
+
+func flacInfo(head []byte, size int64) *MediaInfo {
+	// "fLaC", then a metadata block header; STREAMINFO is always first.
+	if len(head) < 8+18 || head[4]&0x7f != 0 {
+		return nil
+	}
+	si := head[8:]
+	sampleRate := uint32(si[10])<<12 | uint32(si[11])<<4 | uint32(si[12])>>4
+	totalSamples := uint64(si[13]&0x0f)<<32 | uint64(binary.BigEndian.Uint32(si[14:18]))
+	if sampleRate == 0 || totalSamples == 0 {
+		return nil
+	}
+	d := time.Duration(float64(totalSamples) / float64(sampleRate) * float64(time.Second))
+	return (&MediaInfo{Duration: d}).withBitrate(size)
+}
+
+// ── MP3 ──────────────────────────────────────
+
+var (
+	mp3Bitrates = [2][3][16]int{
+		{ // MPEG-1: layer I, II, III
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		},
+		{ // MPEG-2/2.5
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		},
+	}
+	mp3SampleRates = [4][3]int{
+		{11025, 12000, 8000},  // MPEG-2.5
+		{0, 0, 0},             // reserved
+		{22050, 24000, 16000}, // MPEG-2
+		{44100, 48000, 32000}, // MPEG-1
+	}
+)
+
+func mp3Info(f io.ReadSeeker, size int64) *MediaInfo {
+	buf := make([]byte, 16*1024)
+	n, _ := io.ReadFull(f, buf)
+	buf = buf[:n]
+
+	// Skip an ID3v2 tag; its size is a 28-bit syncsafe integer.
+	offset := int64(0)
+	if len(buf) >= 10 && bytes.HasPrefix(buf, []byte("ID3")) {
+		offset = 10 + (int64(buf[6])<<21 | int64(buf[7])<<14 | int64(buf[8])<<7 | int64(buf[9]))
+		if offset+4 > int64(len(buf)) {
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				return nil
+			}
+			n, _ = io.ReadFull(f, buf)
+			buf = buf[:n]
+		} else {
+			buf = buf[offset:]
+		}
+	}
+	audioSize := size - offset
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xff || buf[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version := (buf[i+1] >> 3) & 0x03
+		layer := (buf[i+1] >> 1) & 0x03
+		brIdx := buf[i+2] >> 4
+		srIdx := (buf[i+2] >> 2) & 0x03
+		if version == 1 || layer == 0 || brIdx == 0 || brIdx == 15 || srIdx == 3 {
+			continue
+		}
+		v := 1
+		if version == 3 {
+			v = 0
+		}
+		bitrate := mp3Bitrates[v][3-layer][brIdx] * 1000
+		sampleRate := mp3SampleRates[version][srIdx]
+		samplesPerFrame := 1152
+		switch {
+		case layer == 3: // layer I
+			samplesPerFrame = 384
+		case layer == 1 && v == 1: // layer III, MPEG-2/2.5
+			samplesPerFrame = 576
+		}
+
+		// A Xing/Info header in the first frame carries the frame count
+		// of VBR files.
+		frame := buf[i:]
+		for _, tag := range [][]byte{[]byte("Xing"), []byte("Info")} {
+			if j := bytes.Index(frame[:minInt(len(frame), 64)], tag); j >= 0 && j+12 <= len(frame) {
+				if frame[j+7]&0x01 != 0 {
+					frames := binary.BigEndian.Uint32(frame[j+8 : j+12])
+					d := time.Duration(float64(frames) * float64(samplesPerFrame) / float64(sampleRate) * float64(time.Second))
+					return (&MediaInfo{Duration: d}).withBitrate(audioSize)
+				}
+			}
+		}
+
+		if bitrate == 0 {
+			return nil
+		}
+		d := time.Duration(float64(audioSize*8) / float64(bitrate) * float64(time.Second))
+		return &MediaInfo{Duration: d, Bitrate: int64(bitrate)}
+	}
+	return nil
+}
+
+// ── MP4 / QuickTime ──────────────────────────
+
+func mp4Info(f io.ReadSeeker, size int64) *MediaInfo {
+	m := &MediaInfo{}
+	found := false
+
+	// walk visits the boxes in [start, end), descending into containers.
+	var walk func(start, end int64, depth int)
+	walk = func(start, end int64, depth int) {
+		pos := start
+		for i := 0; pos+8 <= end && i < 512; i++ {
+			if _, err := f.Seek(pos, io.SeekStart); err != nil {
+				return
+			}
+			var hdr [16]byte
+			if _, err := io.ReadFull(f, hdr[:8]); err != nil {
+				return
+			}
+			boxSize := int64(binary.BigEndian.Uint32(hdr[:4]))
+			typ := string(hdr[4:8])
+			headerLen := int64(8)
+			switch boxSize {
+			case 1:
+				if _, err := io.ReadFull(f, hdr[8:16]); err != nil {
+					return
+				}
+				boxSize = int64(binary.BigEndian.Uint64(hdr[8:16]))
+				headerLen = 16
+			case 0:
+				boxSize = end - pos
+			}
+			if boxSize < headerLen || pos+boxSize > end {
+				return
+			}
+
+			body := pos + headerLen
+			switch typ {
+			case "moov", "trak":
+				if depth < 4 {
+					walk(body, pos+boxSize, depth+1)
+				}
+			case "mvhd":
+				if d, ok := readMvhd(f, boxSize-headerLen); ok {
+					m.Duration = d
+					found = true
+				}
+			case "tkhd":
+				if w, h, ok := readTkhd(f, boxSize-headerLen); ok && m.Width == 0 {
+					m.Width, m.Height = w, h
+				}
+			}
+			pos += boxSize
+		}
+	}
+	walk(0, size, 0)
+
+	if !found {
+		return nil
+	}
+	return m.withBitrate(size)
+}
+
+func readMvhd(r io.Reader, n int64) (time.Duration, bool) {
+	buf := make([]byte, minInt64(n, 32))
+	if _, err := io.ReadFull(r, buf); err != nil || len(buf) < 20 {
+		return 0, false
+	}
+	var timescale uint32
+	var duration uint64
+	if buf[0] == 1 {
+		if len(buf) < 32 {
+			return 0, false
+		}
+		timescale = binary.BigEndian.Uint32(buf[20:24])
+		duration = binary.BigEndian.Uint64(buf[24:32])
+	} else {
+		timescale = binary.BigEndian.Uint32(buf[12:16])
+		duration = uint64(binary.BigEndian.Uint32(buf[16:20]))
+	}
+	if timescale == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), true
+}
+
+func readTkhd(r io.Reader, n int64) (int, int, bool) {
+	buf := make([]byte, minInt64(n, 96))
+	if _, err := io.ReadFull(r, buf); err != nil || len(buf) < 84 {
+		return 0, 0, false
+	}
+	// Width and height are 16.16 fixed point and end the box.
+	off := 76
+	if buf[0] == 1 {
+		off = 88
+	}
+	if off+8 > len(buf) {
+		return 0, 0, false
+	}
+	w := int(binary.BigEndian.Uint32(buf[off:off+4]) >> 16)
+	h := int(binary.BigEndian.Uint32(buf[off+4:off+8]) >> 16)
+	return w, h, w > 0 && h > 0
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ── Matroska / WebM ──────────────────────────
+
+const (
+	ebmlSegment       = 0x18538067
+	ebmlInfo          = 0x1549A966
+	ebmlTimecodeScale = 0x2AD7B1
+	ebmlDuration      = 0x4489
+	ebmlTracks        = 0x1654AE6B
+	ebmlTrackEntry    = 0xAE
+	ebmlVideo         = 0xE0
+	ebmlPixelWidth    = 0xB0
+	ebmlPixelHeight   = 0xBA
+	ebmlCluster       = 0x1F43B675
+)
+
+// readEBMLVint decodes a variable-length integer. With keepMarker the
+// length marker bit is kept, as element IDs are written that way.
+func readEBMLVint(buf []byte, keepMarker bool) (uint64, int, bool) {
+	if len(buf) == 0 || buf[0] == 0 {
+		return 0, 0, false
+	}
+	n := 1
+	for mask := byte(0x80); buf[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 || len(buf) < n {
+		return 0, 0, false
+	}
+	v := uint64(buf[0])
+	if !keepMarker {
+		v &= uint64(0xff >> n)
+	}
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(buf[i])
+	}
+	return v, n, true
+}
+
+func matroskaInfo(f io.Reader, size int64) *MediaInfo {
+	buf := make([]byte, mediaReadLimit)
+	n, _ := io.ReadFull(f, buf)
+	buf = buf[:n]
+
+	timecodeScale := uint64(1_000_000)
+	var duration float64
+	m := &MediaInfo{}
+
+	var walk func(b []byte, depth int)
+	walk = func(b []byte, depth int) {
+		for len(b) > 0 {
+			id, idLen, ok := readEBMLVint(b, true)
+			if !ok {
+				return
+			}
+			sz, szLen, ok := readEBMLVint(b[idLen:], false)
+			if !ok {
+				return
+			}
+			b = b[idLen+szLen:]
+			// An all-ones size means "unknown"; treat it as the rest.
+			if sz == (uint64(1)<<(7*szLen))-1 || sz > uint64(len(b)) {
+				sz = uint64(len(b))
+			}
+			data := b[:sz]
+			switch id {
+			case ebmlSegment, ebmlInfo, ebmlTracks, ebmlTrackEntry, ebmlVideo:
+				if depth < 6 {
+					walk(data, depth+1)
+				}
+			case ebmlTimecodeScale:
+				timecodeScale = beUint(data)
+			case ebmlDuration:
+				switch len(data) {
+				case 4:
+					duration = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+				case 8:
+					duration = math.Float64frombits(binary.BigEndian.Uint64(data))
+				}
+			case ebmlPixelWidth:
+				if m.Width == 0 {
+					m.Width = int(beUint(data))
+				}
+			case ebmlPixelHeight:
+				if m.Height == 0 {
+					m.Height = int(beUint(data))
+				}
+			case ebmlCluster:
+				// Media data follows; all headers have been seen.
+				return
+			}
+			b = b[sz:]
+		}
+	}
+	walk(buf, 0)
+
+	if duration > 0 {
+		m.Duration = time.Duration(duration * float64(timecodeScale))
+	}
+	return m.withBitrate(size)
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
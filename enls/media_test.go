@@ -0,0 +1,154 @@
+package enls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// le and be append little- and big-endian integers of the given width.
+func le(b []byte, width int, v uint64) []byte {
+	for i := 0; i < width; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+func be(b []byte, width int, v uint64) []byte {
+	for i := width - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// mp4Box wraps body in an ISO-BMFF box.
+func mp4Box(typ string, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	return append(be([]byte(nil), 4, uint64(8+len(data))), append([]byte(typ), data...)...)
+}
+
+// ebml writes a Matroska element with a one-byte size.
+func ebml(id uint64, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	var b []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if id>>shift != 0 {
+			b = append(b, byte(id>>shift))
+		}
+	}
+	return append(append(b, 0x80|byte(len(data))), data...)
+}
+
+func TestMediaParsers(t *testing.T) {
+	topDown := int32(-480)
+	bmp := le(le([]byte("BM"), 12, 0), 4, 40)
+	bmp = le(le(bmp, 4, 640), 4, uint64(uint32(topDown)))
+	os2 := le(le([]byte("BM"), 12, 0), 4, 12)
+	os2 = append(le(le(os2, 2, 32), 2, 16), make([]byte, 4)...)
+
+	webpLossy := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 \x00\x00\x00\x00"), 0, 0, 0, 0x9d, 0x01, 0x2a)
+	webpLossy = le(le(webpLossy, 2, 320), 2, 240)
+	webpLossless := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00"), 0x2f)
+	webpLossless = le(webpLossless, 4, (100-1)|(50-1)<<14)
+	webpLossless = append(webpLossless, make([]byte, 8)...)
+	webpExtended := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8X\x00\x00\x00\x00"), make([]byte, 4)...)
+	webpExtended = le(le(webpExtended, 3, 1920-1), 3, 1080-1)
+
+	wav := []byte("RIFF\x00\x00\x00\x00WAVELIST")
+	wav = le(wav, 4, 3)
+	wav = append(wav, "abc\x00"...) // odd chunk, padded
+	wav = le(append(wav, "fmt "...), 4, 16)
+	wav = le(le(le(le(le(le(wav, 2, 1), 2, 2), 4, 44100), 4, 176400), 2, 4), 2, 16)
+	wav = le(append(wav, "data"...), 4, 2*176400)
+
+	streamInfo := make([]byte, 34)
+	streamInfo[10], streamInfo[11], streamInfo[12] = 0x0a, 0xc4, 0x40 // 44100 Hz
+	binary.BigEndian.PutUint32(streamInfo[14:], 441000)               // 10 s
+	flac := append([]byte("fLaC\x00\x00\x00\x22"), streamInfo...)
+
+	mp3Frame := []byte{0xff, 0xfb, 0x90, 0x00} // MPEG-1 layer III, 128 kb/s, 44.1 kHz
+	mp3 := append(append([]byte("ID3\x04\x00\x00\x00\x00\x00\x0a"), make([]byte, 10)...), mp3Frame...)
+	xing := append(append(bytes.Clone(mp3Frame), make([]byte, 32)...), "Xing\x00\x00\x00\x01"...)
+	xing = be(xing, 4, 383) // 383 frames of 1152 samples
+
+	mvhd := be(be(be([]byte{0, 0, 0, 0}, 8, 0), 4, 1000), 4, 90_500)
+	tkhd := append(make([]byte, 76), be(be(nil, 4, 1280<<16), 4, 720<<16)...)
+	mp4 := append(mp4Box("ftyp", []byte("isom")),
+		mp4Box("moov", mp4Box("mvhd", mvhd, make([]byte, 80)), mp4Box("trak", mp4Box("tkhd", tkhd)))...)
+
+	durationMs := be(nil, 8, math.Float64bits(5000))
+	mkv := append(ebml(0x1A45DFA3, []byte{0x42, 0x86, 0x81, 0x01}),
+		ebml(ebmlSegment,
+			ebml(ebmlInfo, ebml(ebmlTimecodeScale, be(nil, 3, 1_000_000)), ebml(ebmlDuration, durationMs)),
+			ebml(ebmlTracks, ebml(ebmlTrackEntry, ebml(ebmlVideo,
+				ebml(ebmlPixelWidth, be(nil, 2, 640)), ebml(ebmlPixelHeight, be(nil, 2, 480))))),
+		)...)
+
+	tests := []struct {
+		name string
+		got  *MediaInfo
+		want *MediaInfo // nil when the parser should give up
+	}{
+		{"bmp top-down", bmpInfo(bmp), &MediaInfo{Width: 640, Height: 480}},
+		{"bmp os/2", bmpInfo(os2), &MediaInfo{Width: 32, Height: 16}},
+		{"bmp short", bmpInfo(bmp[:20]), nil},
+		{"webp lossy", webpInfo(webpLossy), &MediaInfo{Width: 320, Height: 240}},
+		{"webp lossless", webpInfo(webpLossless), &MediaInfo{Width: 100, Height: 50}},
+		{"webp extended", webpInfo(webpExtended), &MediaInfo{Width: 1920, Height: 1080}},
+		{"wav", wavInfo(bytes.NewReader(wav), int64(len(wav))+2*176400), &MediaInfo{Duration: 2 * time.Second, Bitrate: 1411200}},
+		{"wav without fmt", wavInfo(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVEdata\x04\x00\x00\x00")), 100), nil},
+		{"flac", flacInfo(flac, 1_000_000), &MediaInfo{Duration: 10 * time.Second, Bitrate: 800_000}},
+		{"flac no samples", flacInfo(append([]byte("fLaC\x00\x00\x00\x22"), make([]byte, 34)...), 100), nil},
+		{"mp3 cbr after id3", mp3Info(bytes.NewReader(mp3), 20+160_000), &MediaInfo{Duration: 10 * time.Second, Bitrate: 128_000}},
+		{"mp3 xing", mp3Info(bytes.NewReader(xing), 160_080), &MediaInfo{Duration: 10_004_897_959, Bitrate: 128_000}},
+		{"mp3 none", mp3Info(bytes.NewReader([]byte("no frames here")), 14), nil},
+		{"mp4", mp4Info(bytes.NewReader(mp4), int64(len(mp4))), &MediaInfo{Width: 1280, Height: 720, Duration: 90500 * time.Millisecond, Bitrate: int64(float64(len(mp4)*8) / 90.5)}},
+		{"mp4 without mvhd", mp4Info(bytes.NewReader(mp4Box("ftyp", []byte("isom"))), 12), nil},
+		{"matroska", matroskaInfo(bytes.NewReader(mkv), 5000), &MediaInfo{Width: 640, Height: 480, Duration: 5 * time.Second, Bitrate: 8000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.want == nil {
+				if tt.got != nil {
+					t.Errorf("got %+v, want nil", *tt.got)
+				}
+				return
+			}
+			if tt.got == nil {
+				t.Fatal("got nil")
+			}
+			got, want := *tt.got, *tt.want
+			// Durations computed through floating point may be a few ns off.
+			if d := got.Duration - want.Duration; d < -time.Microsecond || d > time.Microsecond {
+				t.Errorf("duration %v, want %v", got.Duration, want.Duration)
+			}
+			got.Duration, want.Duration = 0, 0
+			if d := got.Bitrate - want.Bitrate; d < -1 || d > 1 {
+				t.Errorf("bitrate %d, want %d", got.Bitrate, want.Bitrate)
+			}
+			got.Bitrate, want.Bitrate = 0, 0
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMediaInfoString(t *testing.T) {
+	tests := []struct {
+		m    MediaInfo
+		want string
+	}{
+		{MediaInfo{Width: 1920, Height: 1080, Duration: 3723 * time.Second, Bitrate: 5_100_000}, "1920x1080, 1:02:03, 5.1 Mb/s"},
+		{MediaInfo{Duration: 65 * time.Second, Bitrate: 128_000}, "1:05, 128 kb/s"},
+		{MediaInfo{Width: 16, Height: 16}, "16x16"},
+		{MediaInfo{Bitrate: 800}, "800 b/s"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("%+v: %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package enls
+
+import (
+	"io/fs"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ─────────────────────────────────────────────
+// Parallel metadata gathering
+// ─────────────────────────────────────────────
+
+// metadataWorkers bounds the goroutines gathering entry metadata. The work
+// is mostly waiting on lstat, passwd lookups and file reads, so it runs
+// well past the CPU count; on network filesystems this hides latency.
+var metadataWorkers = minInt(64, 8*runtime.NumCPU())
+
+// parallelEach calls fn(i) for every i in [0, n) on at most
+// metadataWorkers goroutines. fn must only write to its own index.
+func parallelEach(n int, fn func(i int)) {
+	workers := minInt(n, metadataWorkers)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// entryItems turns directory entries of dir into items, dropping hidden
+// and --ignore'd names. DirEntry.Info is the one lstat per entry; it
+// reports symbolic links themselves.
+func entryItems(dir string, entries []fs.DirEntry, args *Options) []Entry {
+	all := make([]Entry, len(entries))
+	ok := make([]bool, len(entries))
+	parallelEach(len(entries), func(i int) {
+		entry := entries[i]
+		if isIgnored(entry.Name(), args) {
+			return
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read, most likely.
+			problems.add("cannot access", joinPath(dir, entry.Name()), err)
+			return
+		}
+		all[i] = Entry{FileInfo: info, Path: joinPath(dir, entry.Name())}
+		ok[i] = true
+	})
+	var items []Entry
+	for i := range all {
+		if ok[i] {
+			items = append(items, all[i])
+		}
+	}
+	return items
+}
+
+// listingItems applies the type, search, --mime and --arch filters and
+// fills in owners and link counts for the flat and long listings.
+func listingItems(entries []Entry, args *Options) []Entry {
+	keep := make([]bool, len(entries))
+	parallelEach(len(entries), func(i int) {
+		item := &entries[i]
+		fileType := getFileType(item.FileInfo, item.Path)
+		if !passesFilter(item.FileInfo, item.Path, fileType, args) {
+			return
+		}
+
+		// Archive members carry their owners from the archive.
+		if _, _, inArchive := archiveFor(item.Path); !inArchive {
+			item.OwnerName, item.GroupName = entryOwner(item.Path, item.FileInfo)
+			item.Links = getLinkCount(item.FileInfo)
+		}
+		keep[i] = true
+	})
+	var items []Entry
+	for i, item := range entries {
+		if keep[i] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,124 @@
+package enls
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// MIME types (mime column, --mime filter)
+// ─────────────────────────────────────────────
+
+// kindMIME maps the signatures recognised by detectMagic to MIME types.
+var kindMIME = map[string]string{
+	"elf":       "application/x-executable",
+	"pe":        "application/vnd.microsoft.portable-executable",
+	"macho":     "application/x-mach-binary",
+	"macho-fat": "application/x-mach-binary",
+	"wasm":      "application/wasm",
+	"script":    "text/x-shellscript",
+
+	"zip":      "application/zip",
+	"gzip":     "application/gzip",
+	"bzip2":    "application/x-bzip2",
+	"xz":       "application/x-xz",
+	"zstd":     "application/zstd",
+	"7z":       "application/x-7z-compressed",
+	"rar":      "application/vnd.rar",
+	"lz4":      "application/x-lz4",
+	"lzip":     "application/x-lzip",
+	"compress": "application/x-compress",
+	"cab":      "application/vnd.ms-cab-compressed",
+	"rpm":      "application/x-rpm",
+	"deb":      "application/vnd.debian.binary-package",
+	"xar":      "application/x-xar",
+	"tar":      "application/x-tar",
+
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+	"bmp":  "image/bmp",
+	"tiff": "image/tiff",
+	"ico":  "image/vnd.microsoft.icon",
+	"psd":  "image/vnd.adobe.photoshop",
+	"jxl":  "image/jxl",
+	"webp": "image/webp",
+	"heic": "image/heic",
+	"heif": "image/heif",
+	"avif": "image/avif",
+
+	"mp3":      "audio/mpeg",
+	"flac":     "audio/flac",
+	"ogg":      "audio/ogg",
+	"wav":      "audio/wav",
+	"aiff":     "audio/aiff",
+	"midi":     "audio/midi",
+	"m4a":      "audio/mp4",
+	"matroska": "video/x-matroska",
+	"flv":      "video/x-flv",
+	"asf":      "video/x-ms-asf",
+	"avi":      "video/x-msvideo",
+	"mp4":      "video/mp4",
+	"mov":      "video/quicktime",
+	"3gp":      "video/3gpp",
+}
+
+// fileMIME reports the MIME type of an entry: inode/* for non-regular
+// files, the content signature when the file could be read, and the
+// extension otherwise. Parameters such as "; charset=utf-8" are dropped.
+func fileMIME(info fs.FileInfo, path string) string {
+	mode := info.Mode()
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return "inode/symlink"
+	case mode.IsDir():
+		return "inode/directory"
+	case mode&fs.ModeNamedPipe != 0:
+		return "inode/fifo"
+	case mode&fs.ModeSocket != 0:
+		return "inode/socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "inode/chardevice"
+	case mode&fs.ModeDevice != 0:
+		return "inode/blockdevice"
+	case info.Size() == 0:
+		return "inode/x-empty"
+	}
+
+	byExt := stripMIMEParams(mime.TypeByExtension(strings.ToLower(filepath.Ext(info.Name()))))
+
+	r := readHead(info, path)
+	if r == nil {
+		if byExt != "" {
+			return byExt
+		}
+		return "application/octet-stream"
+	}
+	if r.ok {
+		// Zip is the container for docx, jar, apk and friends.
+		if r.generic && byExt != "" {
+			return byExt
+		}
+		if r.kind == "script" {
+			interp, _ := parseShebang(r.head)
+			return scriptMIME(interp)
+		}
+		return kindMIME[r.kind]
+	}
+
+	detected := stripMIMEParams(http.DetectContentType(r.head))
+	if (detected == "text/plain" || detected == "application/octet-stream") && byExt != "" {
+		return byExt
+	}
+	return detected
+}
+
+func stripMIMEParams(t string) string {
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	return strings.TrimSpace(t)
+}
@@ -0,0 +1,194 @@
+package enls
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// Permissions (octal column, --explain-mode)
+// ─────────────────────────────────────────────
+
+// numericIDs is set by -n: owners are shown as uid/gid numbers.
+var numericIDs bool
+
+// octalMode renders the permission bits with the setuid/setgid/sticky digit,
+// e.g. "0755" or "4755".
+func octalMode(mode fs.FileMode) string {
+	special := 0
+	if mode&fs.ModeSetuid != 0 {
+		special |= 4
+	}
+	if mode&fs.ModeSetgid != 0 {
+		special |= 2
+	}
+	if mode&fs.ModeSticky != 0 {
+		special |= 1
+	}
+	return fmt.Sprintf("%d%03o", special, mode.Perm())
+}
+
+// lsModeString renders mode the way ls -l does ("-rwsr-x--T"), rather than
+// Go's "urwxr-x---" form.
+func lsModeString(mode fs.FileMode) string {
+	b := []byte("----------")
+	switch {
+	case mode.IsDir():
+		b[0] = 'd'
+	case mode&fs.ModeSymlink != 0:
+		b[0] = 'l'
+	case mode&fs.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case mode&fs.ModeSocket != 0:
+		b[0] = 's'
+	case mode&fs.ModeCharDevice != 0:
+		b[0] = 'c'
+	case mode&fs.ModeDevice != 0:
+		b[0] = 'b'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	special := func(i int, set bool, c byte) {
+		if !set {
+			return
+		}
+		if b[i] == 'x' {
+			b[i] = c
+		} else {
+			b[i] = c - 'a' + 'A'
+		}
+	}
+	special(3, mode&fs.ModeSetuid != 0, 's')
+	special(6, mode&fs.ModeSetgid != 0, 's')
+	special(9, mode&fs.ModeSticky != 0, 't')
+	return string(b)
+}
+
+func fileKindName(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symbolic link"
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "block device"
+	}
+	return "regular file"
+}
+
+// permWords describes one rwx triplet. Directories use what the bits mean
+// for them: listing, creating/deleting entries and entering.
+func permWords(bits fs.FileMode, dir bool) string {
+	names := [3]string{"read", "write", "execute"}
+	if dir {
+		names = [3]string{"list", "create/delete entries", "enter"}
+	}
+	var words []string
+	for i, mask := range []fs.FileMode{4, 2, 1} {
+		if bits&mask != 0 {
+			words = append(words, names[i])
+		}
+	}
+	if len(words) == 0 {
+		return "none"
+	}
+	return strings.Join(words, ", ")
+}
+
+// explainMode prints a readable breakdown of the permissions of one file.
+func explainMode(w io.Writer, path string) error {
+	info, err := lstatPath(path)
+	if err != nil {
+		return err
+	}
+	mode := info.Mode()
+	dir := mode.IsDir()
+	owner, group := entryOwner(path, info)
+
+	fmt.Fprintf(w, "%s: %s (%s), %s\n", path, lsModeString(mode), octalMode(mode), fileKindName(mode))
+	if mode&fs.ModeSymlink != 0 {
+		fmt.Fprintln(w, "  permissions of a symbolic link are not used; those of its target apply")
+		return nil
+	}
+	fmt.Fprintf(w, "  owner (%s): %s\n", owner, permWords(mode>>6&7, dir))
+	fmt.Fprintf(w, "  group (%s): %s\n", group, permWords(mode>>3&7, dir))
+	fmt.Fprintf(w, "  others: %s\n", permWords(mode&7, dir))
+
+	noEffect := func(execBit fs.FileMode) string {
+		if mode&execBit == 0 {
+			return " (no execute bit, so it has no effect)"
+		}
+		return ""
+	}
+	if mode&fs.ModeSetuid != 0 {
+		if dir {
+			fmt.Fprintln(w, "  setuid: ignored on directories by most systems")
+		} else {
+			fmt.Fprintln(w, "  setuid: runs with the privileges of the file's owner"+noEffect(0100))
+		}
+	}
+	if mode&fs.ModeSetgid != 0 {
+		if dir {
+			fmt.Fprintln(w, "  setgid: new entries inherit the directory's group")
+		} else {
+			fmt.Fprintln(w, "  setgid: runs with the privileges of the file's group"+noEffect(0010))
+		}
+	}
+	if mode&fs.ModeSticky != 0 {
+		if dir {
+			fmt.Fprintln(w, "  sticky: only an entry's owner can delete or rename it")
+		} else {
+			fmt.Fprintln(w, "  sticky: has no effect on files")
+		}
+	}
+	return nil
+}
+
+// effectiveAccess renders the access column: what the current user can do
+// with the entry (following symbolic links), e.g. "read/write" or
+// "read/traverse" for a directory.
+func effectiveAccess(info fs.FileInfo, path string) string {
+	if !onDisk() {
+		return ""
+	}
+	read, write, exec, ok := accessModes(path)
+	if !ok {
+		return ""
+	}
+	dir := info.IsDir()
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if target, err := statPath(path); err == nil {
+			dir = target.IsDir()
+		}
+	}
+	var words []string
+	if read {
+		words = append(words, "read")
+	}
+	if write {
+		words = append(words, "write")
+	}
+	if exec {
+		if dir {
+			words = append(words, "traverse")
+		} else {
+			words = append(words, "execute")
+		}
+	}
+	if len(words) == 0 {
+		return "none"
+	}
+	return strings.Join(words, "/")
+}
@@ -0,0 +1,107 @@
+//go:build !windows
+
+package enls
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+const detectExecutableByExtension = false
+
+// hasExecBit reports whether the permission bits decide executability.
+const hasExecBit = true
+
+func getFileOwnerGroup(info fs.FileInfo) (string, string) {
+	return lookupOwnerGroup(info, !numericIDs)
+}
+
+// lookupOwnerGroup returns the owner and group of info. Ids without a
+// passwd/group entry, or all ids when resolve is false, stay numeric.
+func lookupOwnerGroup(info fs.FileInfo, resolve bool) (string, string) {
+	sys := info.Sys()
+	stat, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return currentUser, currentUser
+	}
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	gid := strconv.FormatUint(uint64(stat.Gid), 10)
+	if !resolve {
+		return uid, gid
+	}
+	return lookupIDName(userNames, uid, func(id string) (string, error) {
+			u, err := user.LookupId(id)
+			if err != nil {
+				return "", err
+			}
+			return u.Username, nil
+		}),
+		lookupIDName(groupNames, gid, func(id string) (string, error) {
+			g, err := user.LookupGroupId(id)
+			if err != nil {
+				return "", err
+			}
+			return g.Name, nil
+		})
+}
+
+// idNameCache maps numeric ids to names; a directory rarely has more than
+// a handful of owners, so each id is looked up once.
+type idNameCache struct {
+	mu    sync.Mutex
+	names map[string]string
+}
+
+var (
+	userNames  = &idNameCache{names: map[string]string{}}
+	groupNames = &idNameCache{names: map[string]string{}}
+)
+
+// lookupIDName resolves id through c. Ids without an entry stay numeric.
+func lookupIDName(c *idNameCache, id string, lookup func(string) (string, error)) string {
+	c.mu.Lock()
+	name, ok := c.names[id]
+	c.mu.Unlock()
+	if ok {
+		return name
+	}
+	name = id
+	if n, err := lookup(id); err == nil {
+		name = n
+	}
+	c.mu.Lock()
+	c.names[id] = name
+	c.mu.Unlock()
+	return name
+}
+
+func getLinkCount(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	if info.IsDir() {
+		return 2
+	}
+	return 1
+}
+
+func checkExecutable(info fs.FileInfo) bool {
+	return info.Mode()&0111 != 0
+}
+
+// accessModes asks access(2) what the current user may do with path. Unlike
+// the mode bits this accounts for supplementary groups, ACLs and read-only
+// mounts. ok is false when path cannot be reached at all.
+func accessModes(path string) (read, write, exec, ok bool) {
+	const rOK, wOK, xOK, fOK = 4, 2, 1, 0
+	if syscall.Access(path, fOK) != nil {
+		return false, false, false, false
+	}
+	return syscall.Access(path, rOK) == nil,
+		syscall.Access(path, wOK) == nil,
+		syscall.Access(path, xOK) == nil,
+		true
+}
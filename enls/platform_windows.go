@@ -0,0 +1,50 @@
+//go:build windows
+
+package enls
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const detectExecutableByExtension = true
+
+// hasExecBit reports whether the permission bits decide executability.
+const hasExecBit = false
+
+func getFileOwnerGroup(info fs.FileInfo) (string, string) {
+	return currentUser, currentUser
+}
+
+func lookupOwnerGroup(info fs.FileInfo, resolve bool) (string, string) {
+	return currentUser, currentUser
+}
+
+func getLinkCount(info fs.FileInfo) uint64 {
+	if info.IsDir() {
+		return 2
+	}
+	return 1
+}
+
+func checkExecutable(info fs.FileInfo) bool {
+	return false
+}
+
+// accessModes approximates access(2): a file is readable when it opens,
+// writable unless read-only, and executable by extension.
+func accessModes(path string) (read, write, exec, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, false, false, false
+	}
+	if f, err := os.Open(path); err == nil {
+		read = true
+		f.Close()
+	}
+	write = info.Mode().Perm()&0200 != 0
+	exec = info.IsDir() || extTypeMap[strings.ToLower(filepath.Ext(path))] == FileTypeExecutable
+	return read, write, exec, true
+}
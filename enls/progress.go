@@ -0,0 +1,172 @@
+package enls
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ─────────────────────────────────────────────
+// Recursive scan progress & limits (--max-entries, --timeout)
+// ─────────────────────────────────────────────
+
+// progressDelay is how long a scan runs before the progress line appears.
+const progressDelay = time.Second
+
+const progressInterval = 200 * time.Millisecond
+
+// scanState tracks a recursive walk: counts for the progress line and the
+// limits that end it early.
+type scanState struct {
+	mu      sync.Mutex
+	dirs    int
+	entries int
+	current string
+
+	maxEntries int
+	deadline   time.Time
+	truncated  string // why the walk stopped early, if it did
+
+	out    io.Writer // the listing
+	errOut io.Writer // the progress line and truncation notice
+
+	shown     bool // a progress line is on errOut
+	sharedTTY bool // stdout is a terminal too, so output must clear the line
+	stopped   bool
+	done      chan struct{}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// startScan begins a walk for -r or --audit: it applies the limits from
+// args and, when errOut is a terminal, starts the progress line ticker.
+// The walk ends with finish.
+func startScan(args *Options, out, errOut io.Writer) *scanState {
+	s := &scanState{maxEntries: args.MaxEntries, out: out, errOut: errOut}
+	if args.Timeout > 0 {
+		s.deadline = time.Now().Add(args.Timeout)
+	}
+	if isTerminal(errOut) {
+		s.sharedTTY = isTerminal(out)
+		s.done = make(chan struct{})
+		go s.run()
+	}
+	return s
+}
+
+func (s *scanState) run() {
+	start := time.Now()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			if !s.stopped && now.Sub(start) >= progressDelay {
+				s.draw()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// draw writes the progress line; s.mu must be held.
+func (s *scanState) draw() {
+	line := fmt.Sprintf("scanned %d dirs, %d entries: %s", s.dirs, s.entries, s.current)
+	width := 80
+	if f, ok := s.errOut.(*os.File); ok {
+		if w, _, err := term.GetSize(int(f.Fd())); err == nil && w > 0 {
+			width = w
+		}
+	}
+	line = truncateByWidth(line, width-1)
+	fmt.Fprint(s.errOut, "\r\033[K"+line)
+	s.shown = true
+}
+
+// clear erases the progress line; s.mu must be held.
+func (s *scanState) clear() {
+	if s.shown {
+		fmt.Fprint(s.errOut, "\r\033[K")
+		s.shown = false
+	}
+}
+
+// printf writes to the listing without tearing the progress line.
+func (s *scanState) printf(format string, a ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sharedTTY {
+		s.clear()
+	}
+	fmt.Fprintf(s.out, format, a...)
+}
+
+// enterDir records a directory about to be read. It reports false once a
+// limit has been reached.
+func (s *scanState) enterDir(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkLimits() {
+		return false
+	}
+	s.dirs++
+	s.current = path
+	return true
+}
+
+// take accounts for one entry and reports false once a limit is reached.
+func (s *scanState) take() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkLimits() {
+		return false
+	}
+	if s.maxEntries > 0 && s.entries >= s.maxEntries {
+		s.truncated = fmt.Sprintf("--max-entries %d reached", s.maxEntries)
+		return false
+	}
+	s.entries++
+	return true
+}
+
+// checkLimits reports whether the walk may continue; s.mu must be held.
+func (s *scanState) checkLimits() bool {
+	if s.truncated != "" {
+		return false
+	}
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.truncated = "--timeout reached"
+		return false
+	}
+	return true
+}
+
+// finish stops the progress line and reports a truncated walk on stderr.
+// It returns whether the walk was truncated.
+func (s *scanState) finish() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	s.clear()
+	if s.truncated == "" {
+		return false
+	}
+	fmt.Fprintf(s.errOut, "enls: listing truncated after %d entries in %d directories (%s)\n",
+		s.entries, s.dirs, s.truncated)
+	return true
+}
@@ -0,0 +1,121 @@
+package enls
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ─────────────────────────────────────────────
+// Shebang detection
+// ─────────────────────────────────────────────
+
+// interpreterMIME refines the generic script MIME type by interpreter.
+var interpreterMIME = map[string]string{
+	"sh": "text/x-shellscript", "bash": "text/x-shellscript",
+	"zsh": "text/x-shellscript", "dash": "text/x-shellscript",
+	"ksh": "text/x-shellscript", "fish": "text/x-shellscript",
+	"python": "text/x-python", "perl": "text/x-perl",
+	"ruby": "text/x-ruby", "node": "text/javascript",
+	"deno": "text/javascript", "php": "text/x-php",
+	"lua": "text/x-lua", "tclsh": "text/x-tcl",
+	"awk": "text/x-awk", "pwsh": "text/x-powershell",
+}
+
+// parseShebang returns the interpreter named on a "#!" line: the base name
+// of the program, or of its first argument when the program is env
+// ("#!/usr/bin/env -S python3 -u" → "python3").
+func parseShebang(head []byte) (string, bool) {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return "", false
+	}
+	line := head[2:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(strings.TrimSuffix(string(line), "\r"))
+	if len(fields) == 0 {
+		return "", false
+	}
+	prog := path.Base(fields[0])
+	if prog == "env" {
+		prog = ""
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue
+			}
+			prog = path.Base(f)
+			break
+		}
+		if prog == "" {
+			return "", false
+		}
+	}
+	return prog, true
+}
+
+// The -l script note only opens files that could be scripts: small ones
+// with no extension or a script extension, at most scriptNoteLimit per
+// directory. This budget is separate from the --sniff=N limit.
+const (
+	scriptNoteMaxSize = 1 << 20
+	scriptNoteLimit   = 200
+	shebangLen        = 256
+)
+
+var scriptExts = map[string]bool{
+	".sh": true, ".bash": true, ".zsh": true, ".ksh": true, ".fish": true,
+	".command": true, ".py": true, ".pl": true, ".rb": true, ".js": true,
+	".mjs": true, ".php": true, ".lua": true, ".tcl": true, ".awk": true,
+	".ps1": true,
+}
+
+var (
+	scriptNoteMu     sync.Mutex
+	scriptNotePerDir = map[string]int{}
+)
+
+// scriptInterpreter reads the shebang of a regular file, if any.
+func scriptInterpreter(info fs.FileInfo, p string) (string, bool) {
+	if info.Size() == 0 || info.Size() > scriptNoteMaxSize {
+		return "", false
+	}
+	if ext := strings.ToLower(filepath.Ext(info.Name())); ext != "" && !scriptExts[ext] {
+		return "", false
+	}
+	// --sniff may already have read the file.
+	if r := cachedSniff(info, p); r != nil {
+		return parseShebang(r.head)
+	}
+
+	dir := filepath.Dir(p)
+	scriptNoteMu.Lock()
+	if scriptNotePerDir[dir] >= scriptNoteLimit {
+		scriptNoteMu.Unlock()
+		return "", false
+	}
+	scriptNotePerDir[dir]++
+	scriptNoteMu.Unlock()
+
+	f, err := openPath(p)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	head := make([]byte, shebangLen)
+	n, _ := io.ReadFull(f, head)
+	return parseShebang(head[:n])
+}
+
+// scriptMIME maps an interpreter such as "python3.11" to a MIME type.
+func scriptMIME(interp string) string {
+	name := strings.TrimRight(interp, "0123456789.")
+	if t, ok := interpreterMIME[name]; ok {
+		return t
+	}
+	return "text/x-script"
+}
@@ -0,0 +1,247 @@
+package enls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────
+// Content sniffing (--sniff)
+// ─────────────────────────────────────────────
+
+// sniffLen is how much of each file is read; every signature below fits.
+const sniffLen = 512
+
+// defaultSniffLimit bounds how many files are opened per directory.
+const defaultSniffLimit = 1000
+
+type magicSignature struct {
+	offset int
+	magic  []byte
+	kind   string
+	ft     FileType
+	// generic containers (zip) only decide the type when the extension
+	// does not already say something more specific (.docx, .jar, .apk).
+	generic bool
+}
+
+var magicSignatures = []magicSignature{
+	// Executables.
+	{0, []byte("\x7fELF"), "elf", FileTypeExecutable, false},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xce}, "macho", FileTypeExecutable, false},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xcf}, "macho", FileTypeExecutable, false},
+	{0, []byte{0xce, 0xfa, 0xed, 0xfe}, "macho", FileTypeExecutable, false},
+	{0, []byte{0xcf, 0xfa, 0xed, 0xfe}, "macho", FileTypeExecutable, false},
+	{0, []byte("\x00asm"), "wasm", FileTypeExecutable, false},
+	// A shebang alone does not make a file runnable: sniffFileType leaves
+	// scripts to the exec bit and the extension.
+	{0, []byte("#!"), "script", FileTypeOther, false},
+
+	// Archives and compressed data.
+	{0, []byte("PK\x03\x04"), "zip", FileTypeArchive, true},
+	{0, []byte("PK\x05\x06"), "zip", FileTypeArchive, true},
+	{0, []byte{0x1f, 0x8b}, "gzip", FileTypeArchive, false},
+	{0, []byte("BZh"), "bzip2", FileTypeArchive, false},
+	{0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "xz", FileTypeArchive, false},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, "zstd", FileTypeArchive, false},
+	{0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, "7z", FileTypeArchive, false},
+	{0, []byte("Rar!\x1a\x07"), "rar", FileTypeArchive, false},
+	{0, []byte{0x04, 0x22, 0x4d, 0x18}, "lz4", FileTypeArchive, false},
+	{0, []byte("LZIP"), "lzip", FileTypeArchive, false},
+	{0, []byte{0x1f, 0x9d}, "compress", FileTypeArchive, false},
+	{0, []byte("MSCF"), "cab", FileTypeArchive, false},
+	{0, []byte{0xed, 0xab, 0xee, 0xdb}, "rpm", FileTypeArchive, false},
+	{0, []byte("!<arch>\ndebian"), "deb", FileTypeArchive, false},
+	{0, []byte("xar!"), "xar", FileTypeArchive, false},
+	{257, []byte("ustar"), "tar", FileTypeArchive, false},
+
+	// Images.
+	{0, []byte("\x89PNG\r\n\x1a\n"), "png", FileTypeMedia, false},
+	{0, []byte{0xff, 0xd8, 0xff}, "jpeg", FileTypeMedia, false},
+	{0, []byte("GIF87a"), "gif", FileTypeMedia, false},
+	{0, []byte("GIF89a"), "gif", FileTypeMedia, false},
+	{0, []byte("II*\x00"), "tiff", FileTypeMedia, false},
+	{0, []byte("MM\x00*"), "tiff", FileTypeMedia, false},
+	{0, []byte{0x00, 0x00, 0x01, 0x00}, "ico", FileTypeMedia, false},
+	{0, []byte("8BPS"), "psd", FileTypeMedia, false},
+	{0, []byte{0xff, 0x0a}, "jxl", FileTypeMedia, false},
+
+	// Audio / video.
+	{0, []byte("ID3"), "mp3", FileTypeMedia, false},
+	{0, []byte("fLaC"), "flac", FileTypeMedia, false},
+	{0, []byte("OggS"), "ogg", FileTypeMedia, false},
+	{0, []byte{0x1a, 0x45, 0xdf, 0xa3}, "matroska", FileTypeMedia, false},
+	{0, []byte("FLV"), "flv", FileTypeMedia, false},
+	{0, []byte{0x30, 0x26, 0xb2, 0x75, 0x8e, 0x66, 0xcf, 0x11}, "asf", FileTypeMedia, false},
+	{0, []byte("MThd"), "midi", FileTypeMedia, false},
+}
+
+// riffKinds and ftypKinds cover containers whose sub-type sits a few bytes
+// in: RIFF....WAVE and ....ftypisom.
+var riffKinds = map[string]string{
+	"WAVE": "wav",
+	"AVI ": "avi",
+	"WEBP": "webp",
+}
+
+var ftypKinds = map[string]string{
+	"heic": "heic", "heix": "heic", "mif1": "heif", "msf1": "heif",
+	"avif": "avif", "avis": "avif",
+	"qt  ": "mov",
+	"M4A ": "m4a", "M4B ": "m4a",
+	"3gp4": "3gp", "3gp5": "3gp", "3g2a": "3gp",
+}
+
+// sniffResult is the cached outcome for one file.
+type sniffResult struct {
+	kind    string
+	ft      FileType
+	generic bool
+	ok      bool
+	head    []byte
+	size    int64
+	modTime time.Time
+}
+
+var (
+	// sniffEnabled and sniffLimit are set in main from --sniff.
+	sniffEnabled bool
+	sniffLimit   = defaultSniffLimit
+
+	sniffMu     sync.Mutex
+	sniffCache  = map[string]*sniffResult{}
+	sniffPerDir = map[string]int{}
+)
+
+// detectMagic matches head against the known signatures.
+func detectMagic(head []byte) (kind string, ft FileType, generic bool, ok bool) {
+	if len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) {
+		if k, found := riffKinds[string(head[8:12])]; found {
+			return k, FileTypeMedia, false, true
+		}
+	}
+	if len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) {
+		if k, found := ftypKinds[string(head[8:12])]; found {
+			return k, FileTypeMedia, false, true
+		}
+		return "mp4", FileTypeMedia, false, true
+	}
+	if len(head) >= 12 && bytes.Equal(head[:4], []byte("FORM")) &&
+		(bytes.Equal(head[8:12], []byte("AIFF")) || bytes.Equal(head[8:12], []byte("AIFC"))) {
+		return "aiff", FileTypeMedia, false, true
+	}
+	// Two-byte magics need a second look to avoid matching plain text.
+	if len(head) >= 18 && head[0] == 'B' && head[1] == 'M' {
+		switch binary.LittleEndian.Uint32(head[14:18]) {
+		case 12, 40, 52, 56, 64, 108, 124:
+			return "bmp", FileTypeMedia, false, true
+		}
+	}
+	if len(head) >= 64 && head[0] == 'M' && head[1] == 'Z' {
+		return "pe", FileTypeExecutable, false, true
+	}
+	// Java class files share CAFEBABE; their version numbers make the
+	// following word far larger than any fat Mach-O architecture count.
+	if len(head) >= 8 && bytes.Equal(head[:4], []byte{0xca, 0xfe, 0xba, 0xbe}) {
+		if n := binary.BigEndian.Uint32(head[4:8]); n > 0 && n <= 30 {
+			return "macho-fat", FileTypeExecutable, false, true
+		}
+	}
+	for _, sig := range magicSignatures {
+		end := sig.offset + len(sig.magic)
+		if len(head) >= end && bytes.Equal(head[sig.offset:end], sig.magic) {
+			return sig.kind, sig.ft, sig.generic, true
+		}
+	}
+	// MPEG audio frame sync without an ID3 tag. FF FE is a UTF-16LE byte
+	// order mark far more often than an MPEG-1 layer I frame.
+	if len(head) >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0 && head[1]&0x06 != 0 && head[1] != 0xfe {
+		return "mp3", FileTypeMedia, false, true
+	}
+	return "", FileTypeOther, false, false
+}
+
+// cachedSniff returns the cached sniff result for path, if it is current.
+func cachedSniff(info fs.FileInfo, path string) *sniffResult {
+	sniffMu.Lock()
+	defer sniffMu.Unlock()
+	if r, ok := sniffCache[path]; ok && r.size == info.Size() && r.modTime.Equal(info.ModTime()) {
+		return r
+	}
+	return nil
+}
+
+// readHead returns the content classification of a regular file, reading
+// at most sniffLen bytes once per file. Columns and filters the user asked
+// for (--arch, mime, media, ...) read every file they need; only the
+// --sniff type detection in sniffFile is budgeted. It returns nil when the
+// file could not be read.
+func readHead(info fs.FileInfo, path string) *sniffResult {
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return nil
+	}
+	if r := cachedSniff(info, path); r != nil {
+		return r
+	}
+
+	f, err := openPath(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil
+	}
+	head = head[:n]
+
+	r := &sniffResult{head: head, size: info.Size(), modTime: info.ModTime()}
+	r.kind, r.ft, r.generic, r.ok = detectMagic(head)
+
+	sniffMu.Lock()
+	sniffCache[path] = r
+	sniffMu.Unlock()
+	return r
+}
+
+// sniffFile is readHead for --sniff type detection: it opens at most
+// sniffLimit files per directory and returns nil beyond that.
+func sniffFile(info fs.FileInfo, path string) *sniffResult {
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return nil
+	}
+	if r := cachedSniff(info, path); r != nil {
+		return r
+	}
+	sniffMu.Lock()
+	dir := filepath.Dir(path)
+	if sniffPerDir[dir] >= sniffLimit {
+		sniffMu.Unlock()
+		return nil
+	}
+	sniffPerDir[dir]++
+	sniffMu.Unlock()
+	return readHead(info, path)
+}
+
+// sniffFileType is the --sniff step of getFileType. extFT is what the
+// extension tables say, if anything.
+func sniffFileType(info fs.FileInfo, path string, extFT FileType, extKnown bool) (FileType, bool) {
+	if !sniffEnabled {
+		return FileTypeOther, false
+	}
+	r := sniffFile(info, path)
+	if r == nil || !r.ok || r.kind == "script" {
+		return FileTypeOther, false
+	}
+	if r.generic && extKnown {
+		return extFT, true
+	}
+	return r.ft, true
+}
@@ -0,0 +1,78 @@
+package enls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDetectMagic(t *testing.T) {
+	pad := func(b []byte, n int) []byte {
+		return append(b, make([]byte, max(0, n-len(b)))...)
+	}
+	bmp := pad([]byte("BM"), 18)
+	binary.LittleEndian.PutUint32(bmp[14:], 40)
+	tar := pad(nil, 512)
+	copy(tar[257:], "ustar")
+	fat := []byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 2}
+	javaClass := []byte{0xca, 0xfe, 0xba, 0xbe, 0, 0, 0, 52}
+
+	tests := []struct {
+		name    string
+		head    []byte
+		kind    string // "" when nothing should match
+		ft      FileType
+		generic bool
+	}{
+		{"elf", []byte("\x7fELF\x02\x01\x01"), "elf", FileTypeExecutable, false},
+		{"pe", pad([]byte("MZ"), 64), "pe", FileTypeExecutable, false},
+		{"short MZ is text", []byte("MZ is a prefix"), "", FileTypeOther, false},
+		{"macho 64", []byte{0xcf, 0xfa, 0xed, 0xfe}, "macho", FileTypeExecutable, false},
+		{"macho fat", fat, "macho-fat", FileTypeExecutable, false},
+		{"java class", javaClass, "", FileTypeOther, false},
+		{"shebang", []byte("#!/bin/sh\n"), "script", FileTypeOther, false},
+		{"zip", []byte("PK\x03\x04rest"), "zip", FileTypeArchive, true},
+		{"gzip", []byte{0x1f, 0x8b, 0x08}, "gzip", FileTypeArchive, false},
+		{"xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "xz", FileTypeArchive, false},
+		{"tar", tar, "tar", FileTypeArchive, false},
+		{"png", []byte("\x89PNG\r\n\x1a\n"), "png", FileTypeMedia, false},
+		{"bmp", bmp, "bmp", FileTypeMedia, false},
+		{"BM text", []byte("BMW owners manual, 2nd edition"), "", FileTypeOther, false},
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), "wav", FileTypeMedia, false},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "webp", FileTypeMedia, false},
+		{"unknown riff", []byte("RIFF\x24\x00\x00\x00XXXX"), "", FileTypeOther, false},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom"), "mp4", FileTypeMedia, false},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic"), "heic", FileTypeMedia, false},
+		{"aiff", []byte("FORM\x00\x00\x00\x00AIFF"), "aiff", FileTypeMedia, false},
+		{"mp3 frame sync", []byte{0xff, 0xfb, 0x90, 0x00}, "mp3", FileTypeMedia, false},
+		{"utf-16 bom", []byte{0xff, 0xfe, 'a', 0x00}, "", FileTypeOther, false},
+		{"text", []byte("hello, world\n"), "", FileTypeOther, false},
+		{"empty", nil, "", FileTypeOther, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, ft, generic, ok := detectMagic(tt.head)
+			if tt.kind == "" {
+				if ok {
+					t.Errorf("matched %q, want no match", kind)
+				}
+				return
+			}
+			if !ok || kind != tt.kind || ft != tt.ft || generic != tt.generic {
+				t.Errorf("got %q %v generic=%v ok=%v, want %q %v generic=%v", kind, ft, generic, ok, tt.kind, tt.ft, tt.generic)
+			}
+		})
+	}
+}
+
+// Every signature in the table must be reachable: none may be shadowed by
+// an earlier, shorter one.
+func TestMagicSignaturesReachable(t *testing.T) {
+	for _, sig := range magicSignatures {
+		head := append(bytes.Repeat([]byte{' '}, sig.offset), sig.magic...)
+		head = append(head, bytes.Repeat([]byte{' '}, 16)...)
+		if kind, _, _, ok := detectMagic(head); !ok || kind != sig.kind {
+			t.Errorf("%q at %d detected as %q", sig.magic, sig.offset, kind)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package enls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// ─────────────────────────────────────────────
+// Exit status & error summary
+// ─────────────────────────────────────────────
+
+// Exit statuses follow GNU ls.
+const (
+	ExitOK          = 0
+	ExitMinor       = 1   // e.g. a subdirectory could not be read
+	ExitSerious     = 2   // e.g. bad usage or an inaccessible path argument
+	ExitInterrupted = 130 // 128 + SIGINT
+)
+
+// problemLog collects the access errors met while listing, so they are
+// reported together after the output rather than interleaved with it.
+type problemLog struct {
+	mu   sync.Mutex
+	errs []string
+}
+
+// problems is the log of the Lister whose settings are installed.
+var problems = &problemLog{}
+
+// add records a failed operation on path, e.g. add("cannot read directory",
+// path, err).
+func (p *problemLog) add(what, path string, err error) {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	p.mu.Lock()
+	p.errs = append(p.errs, fmt.Sprintf("%s %s: %v", what, path, err))
+	p.mu.Unlock()
+}
+
+// ReportProblems writes the access errors l met since the last report to
+// w and returns how many there were.
+func (l *Lister) ReportProblems(w io.Writer) int {
+	p := &l.problems
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.errs {
+		fmt.Fprintf(w, "enls: %s\n", e)
+	}
+	if len(p.errs) > 1 {
+		fmt.Fprintf(w, "enls: %d paths could not be accessed\n", len(p.errs))
+	}
+	n := len(p.errs)
+	p.errs = nil
+	return n
+}
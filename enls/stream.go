@@ -0,0 +1,172 @@
+package enls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// Streaming output (-U / --stream)
+// ─────────────────────────────────────────────
+//
+// Entries are read with File.ReadDir in batches and printed as soon as
+// each batch is ready, unsorted, so memory stays bounded by the batch size.
+// The flat layout becomes one entry per line and the long layout drops the
+// table borders, whose widths would have to be known up front.
+
+// streamBatch is how many entries are read and printed at a time.
+const streamBatch = 1024
+
+// longStream prints -l rows without borders. Column widths only grow, so
+// rows after a wider one shift right rather than being re-aligned.
+type longStream struct {
+	w       io.Writer
+	args    *Options
+	columns []longColumn
+	widths  []int
+	idx     int
+}
+
+func newLongStream(w io.Writer, args *Options) *longStream {
+	s := &longStream{w: w, args: args, columns: longColumnsFor(args)}
+	s.widths = make([]int, len(s.columns))
+	for c, col := range s.columns {
+		s.widths[c] = maxInt(col.minWidth, getStringDisplayWidth(col.header))
+	}
+	return s
+}
+
+func (s *longStream) write(items []Entry) {
+	w := s.w
+	rows, cells, extras := buildLongRows(items, s.columns, s.args)
+	for i := range rows {
+		for c := range s.columns {
+			s.widths[c] = maxInt(s.widths[c], getStringDisplayWidth(cells[i][c]))
+		}
+	}
+	useColor := colorEnabled && s.args.SetColor
+
+	if s.idx == 0 {
+		fields := []string{"#"}
+		for c, col := range s.columns {
+			fields = append(fields, padByWidth(col.header, s.widths[c]))
+		}
+		header := strings.TrimRight(strings.Join(fields, "  "), " ")
+		if useColor && tableHeaderColor != "" {
+			header = tableHeaderColor + header + ansiReset
+		}
+		fmt.Fprintln(w, header)
+	}
+
+	for i := range rows {
+		fields := []string{strconv.Itoa(s.idx)}
+		s.idx++
+		for c, col := range s.columns {
+			cell := cells[i][c]
+			padding := strings.Repeat(" ", maxInt(0, s.widths[c]-getStringDisplayWidth(cell)))
+			if useColor && col.paint != nil && cell != "" {
+				cell = col.paint(&rows[i], cell)
+			}
+			if col.alignRight {
+				fields = append(fields, padding+cell)
+			} else {
+				fields = append(fields, cell+padding)
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(fields, "  "), " "))
+		for _, line := range extras[i] {
+			fmt.Fprintln(w, "  "+line)
+		}
+	}
+}
+
+// dropFileCaches forgets what was read from individual files (sniffed
+// heads, binary headers, xattr names). Each is only looked up while its
+// row is built, so streaming clears them after every batch and tree walks
+// after every directory; otherwise they would grow with the listing. The
+// per-directory sniff counts are kept so --sniff=N still covers the whole
+// directory.
+func dropFileCaches() {
+	sniffMu.Lock()
+	sniffCache = map[string]*sniffResult{}
+	sniffMu.Unlock()
+	binaryMu.Lock()
+	binaryCache = map[string]*BinaryInfo{}
+	binaryMu.Unlock()
+	xattrMu.Lock()
+	xattrCache = map[string][]string{}
+	xattrMu.Unlock()
+}
+
+// dropDirBudgets forgets the --sniff and shebang read counts of dir once
+// its entries have been classified, so a tree walk does not keep one per
+// directory it visited.
+func dropDirBudgets(dir string) {
+	dir = filepath.Clean(dir)
+	sniffMu.Lock()
+	delete(sniffPerDir, dir)
+	sniffMu.Unlock()
+	scriptNoteMu.Lock()
+	delete(scriptNotePerDir, dir)
+	scriptNoteMu.Unlock()
+}
+
+// streamDir lists dir unsorted, batch by batch.
+func streamDir(ctx context.Context, w io.Writer, dir string, args *Options) error {
+	file, err := openPath(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	f, ok := file.(fs.ReadDirFile)
+	if !ok {
+		return &fs.PathError{Op: "readdir", Path: dir, Err: errors.New("not implemented")}
+	}
+
+	var long *longStream
+	if args.LongFormat {
+		long = newLongStream(w, args)
+	}
+
+	shown := 0
+	for ctx.Err() == nil {
+		entries, err := f.ReadDir(streamBatch)
+		items := listingItems(entryItems(dir, entries, args), args)
+		shown += len(items)
+
+		if long != nil {
+			if len(items) > 0 {
+				long.write(items)
+			}
+		} else {
+			for _, item := range items {
+				name, _ := itemDisplayName(item, args)
+				fmt.Fprintln(w, name)
+				if args.XAttr {
+					for _, line := range xattrLines(item.Path) {
+						fmt.Fprintln(w, "    "+line)
+					}
+				}
+			}
+		}
+		dropFileCaches()
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if shown == 0 && ctx.Err() == nil {
+		fmt.Fprintln(w, "No matching files found")
+	}
+	return nil
+}
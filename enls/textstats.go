@@ -0,0 +1,195 @@
+package enls
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"unicode/utf8"
+)
+
+// ─────────────────────────────────────────────
+// Text statistics (lines, encoding, eol columns)
+// ─────────────────────────────────────────────
+
+// textStatsLimit skips files too large to be worth counting line by line.
+const textStatsLimit = 64 << 20
+
+// TextStats describes a text file. Encoding is "binary" for files with NUL
+// bytes or byte sequences that are neither UTF-8 nor GBK; the other fields
+// are then unset.
+type TextStats struct {
+	Lines    int
+	Encoding string // UTF-8, UTF-8 BOM, UTF-16LE, UTF-16BE, GBK, binary
+	EOL      string // LF, CRLF, CR, mixed, or "" without line breaks
+}
+
+// textStats reads a regular file once and gathers its statistics. Files
+// recognised by their signature as images, archives or executables are not
+// read at all.
+func textStats(info fs.FileInfo, path string) *TextStats {
+	if !info.Mode().IsRegular() || info.Size() > textStatsLimit {
+		return nil
+	}
+	if info.Size() == 0 {
+		return &TextStats{}
+	}
+	if r := readHead(info, path); r != nil && r.ok && r.kind != "script" {
+		return &TextStats{Encoding: "binary"}
+	}
+
+	f, err := openPath(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	br := bufio.NewReaderSize(f, 64*1024)
+
+	st := &TextStats{Encoding: "UTF-8"}
+	if bom, _ := br.Peek(3); bytes.HasPrefix(bom, []byte{0xef, 0xbb, 0xbf}) {
+		st.Encoding = "UTF-8 BOM"
+		br.Discard(3)
+	} else if bytes.HasPrefix(bom, []byte{0xff, 0xfe}) {
+		st.Encoding = "UTF-16LE"
+		br.Discard(2)
+	} else if bytes.HasPrefix(bom, []byte{0xfe, 0xff}) {
+		st.Encoding = "UTF-16BE"
+		br.Discard(2)
+	}
+	utf16 := st.Encoding == "UTF-16LE" || st.Encoding == "UTF-16BE"
+
+	var (
+		lf, crlf, cr int
+		prevCR       bool
+		last         uint16 // last code unit, to count an unterminated line
+		validUTF8    = true
+		validGBK     = true
+		pending      []byte // odd UTF-16 byte, or a sequence split across reads
+		pendingGBK   []byte
+	)
+
+	unit := func(u uint16) {
+		switch u {
+		case '\n':
+			if prevCR {
+				crlf++
+				cr--
+			} else {
+				lf++
+			}
+		case '\r':
+			cr++
+		}
+		prevCR = u == '\r'
+		last = u
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := io.ReadFull(br, buf)
+		chunk := buf[:n]
+		if utf16 {
+			if len(pending) > 0 {
+				chunk = append(pending, chunk...)
+				pending = nil
+			}
+			if len(chunk)%2 == 1 {
+				pending = []byte{chunk[len(chunk)-1]}
+				chunk = chunk[:len(chunk)-1]
+			}
+			for i := 0; i+1 < len(chunk); i += 2 {
+				if st.Encoding == "UTF-16LE" {
+					unit(uint16(chunk[i]) | uint16(chunk[i+1])<<8)
+				} else {
+					unit(uint16(chunk[i])<<8 | uint16(chunk[i+1]))
+				}
+			}
+		} else {
+			if bytes.IndexByte(chunk, 0) >= 0 {
+				return &TextStats{Encoding: "binary"}
+			}
+			for _, c := range chunk {
+				unit(uint16(c))
+			}
+			atEOF := err != nil
+			if validUTF8 {
+				validUTF8, pending = checkUTF8(append(pending, chunk...), atEOF)
+			}
+			if validGBK {
+				validGBK, pendingGBK = checkGBK(append(pendingGBK, chunk...), atEOF)
+			}
+			if !validUTF8 && !validGBK {
+				return &TextStats{Encoding: "binary"}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if !utf16 && !validUTF8 {
+		st.Encoding = "GBK"
+	}
+	st.Lines = lf + crlf + cr
+	if last != '\n' && last != '\r' && last != 0 {
+		st.Lines++
+	}
+	switch {
+	case lf+crlf+cr == 0:
+	case lf == 0 && cr == 0:
+		st.EOL = "CRLF"
+	case crlf == 0 && cr == 0:
+		st.EOL = "LF"
+	case lf == 0 && crlf == 0:
+		st.EOL = "CR"
+	default:
+		st.EOL = "mixed"
+	}
+	return st
+}
+
+// checkUTF8 validates data, returning a trailing incomplete sequence to be
+// prepended to the next chunk.
+func checkUTF8(data []byte, atEOF bool) (bool, []byte) {
+	for i := 0; i < len(data); {
+		if data[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(data[i:]) {
+				return true, append([]byte(nil), data[i:]...)
+			}
+			return false, nil
+		}
+		i += size
+	}
+	return true, nil
+}
+
+// checkGBK checks that data is made of ASCII and GBK double-byte pairs.
+// GB18030 four-byte sequences are rare enough in practice to ignore.
+func checkGBK(data []byte, atEOF bool) (bool, []byte) {
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c < 0x80 {
+			continue
+		}
+		if c == 0x80 || c == 0xff {
+			return false, nil
+		}
+		if i+1 == len(data) {
+			if atEOF {
+				return false, nil
+			}
+			return true, []byte{c}
+		}
+		t := data[i+1]
+		if t < 0x40 || t == 0x7f || t == 0xff {
+			return false, nil
+		}
+		i++
+	}
+	return true, nil
+}
@@ -0,0 +1,548 @@
+package enls
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ─────────────────────────────────────────────
+// Colors & styles
+// ─────────────────────────────────────────────
+
+// ColorDepth is the palette size the terminal is assumed to support.
+type ColorDepth int
+
+const (
+	ColorDepthAuto ColorDepth = iota
+	ColorDepth16
+	ColorDepth256
+	ColorDepthTrue
+)
+
+type colorKind int
+
+const (
+	colorNone    colorKind = iota
+	colorBasic             // 0-15, the ANSI palette
+	colorIndexed           // 0-255, the xterm palette
+	colorRGB
+)
+
+// Color is a foreground or background color as written in a theme.
+type Color struct {
+	kind    colorKind
+	index   int
+	r, g, b uint8
+}
+
+// Style is a set of SGR attributes plus optional colors.
+type Style struct {
+	Attrs []int
+	FG    Color
+	BG    Color
+}
+
+// colorDepth is resolved in main from --color-depth or the environment.
+var colorDepth = ColorDepth16
+
+var basicColorNames = map[string]int{
+	"black": 0, "red": 1, "green": 2, "yellow": 3,
+	"blue": 4, "magenta": 5, "cyan": 6, "white": 7,
+}
+
+var styleAttrNames = map[string]int{
+	"bold": 1, "dim": 2, "faint": 2, "italic": 3, "underline": 4,
+	"blink": 5, "reverse": 7, "strike": 9,
+}
+
+// xterm's default RGB values for the 16 basic colors, used to downsample.
+var basicPalette = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// parseColor accepts a basic name ("blue", "bright-blue", "gray"), an xterm
+// index ("208") or a hex triplet ("#268bd2", "#fff").
+func parseColor(s string) (Color, error) {
+	name := strings.ToLower(s)
+	if name == "default" || name == "none" {
+		return Color{}, nil
+	}
+	if name == "gray" || name == "grey" {
+		return Color{kind: colorBasic, index: 8}, nil
+	}
+	for _, prefix := range []string{"bright-", "bright_", "bright"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			if n, ok := basicColorNames[rest]; ok {
+				return Color{kind: colorBasic, index: n + 8}, nil
+			}
+		}
+	}
+	if n, ok := basicColorNames[name]; ok {
+		return Color{kind: colorBasic, index: n}, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 0 && n <= 255 {
+		return Color{kind: colorIndexed, index: n}, nil
+	}
+	if hex, ok := strings.CutPrefix(name, "#"); ok {
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && len(hex) == 6 {
+			return Color{kind: colorRGB, r: uint8(v >> 16), g: uint8(v >> 8), b: uint8(v)}, nil
+		}
+	}
+	return Color{}, fmt.Errorf("unknown color %q", s)
+}
+
+// parseStyle parses a space-separated style such as "bold #268bd2",
+// "dim yellow" or "black on bright-white".
+func parseStyle(spec string) (Style, error) {
+	var st Style
+	background := false
+	for _, tok := range strings.Fields(spec) {
+		lower := strings.ToLower(tok)
+		if lower == "on" {
+			background = true
+			continue
+		}
+		if attr, ok := styleAttrNames[lower]; ok {
+			st.Attrs = append(st.Attrs, attr)
+			continue
+		}
+		c, err := parseColor(tok)
+		if err != nil {
+			return Style{}, err
+		}
+		if background {
+			st.BG = c
+			background = false
+		} else {
+			st.FG = c
+		}
+	}
+	return st, nil
+}
+
+// Seq renders the style as an escape sequence for the current colorDepth,
+// or "" when it sets nothing.
+func (st Style) Seq() string {
+	params := make([]string, 0, len(st.Attrs)+2)
+	for _, a := range st.Attrs {
+		params = append(params, strconv.Itoa(a))
+	}
+	if p := st.FG.params(false, colorDepth); p != "" {
+		params = append(params, p)
+	}
+	if p := st.BG.params(true, colorDepth); p != "" {
+		params = append(params, p)
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+func (c Color) params(bg bool, depth ColorDepth) string {
+	switch c.kind {
+	case colorBasic:
+		return basicParam(c.index, bg)
+	case colorIndexed:
+		if depth == ColorDepth256 || depth == ColorDepthTrue {
+			return fmt.Sprintf("%d;5;%d", extParam(bg), c.index)
+		}
+		return basicParam(indexedToBasic(c.index), bg)
+	case colorRGB:
+		switch depth {
+		case ColorDepthTrue:
+			return fmt.Sprintf("%d;2;%d;%d;%d", extParam(bg), c.r, c.g, c.b)
+		case ColorDepth256:
+			return fmt.Sprintf("%d;5;%d", extParam(bg), rgbTo256(c.r, c.g, c.b))
+		default:
+			return basicParam(nearestBasic(c.r, c.g, c.b), bg)
+		}
+	}
+	return ""
+}
+
+func basicParam(n int, bg bool) string {
+	base := 30
+	if n >= 8 {
+		base = 90
+		n -= 8
+	}
+	if bg {
+		base += 10
+	}
+	return strconv.Itoa(base + n)
+}
+
+func extParam(bg bool) int {
+	if bg {
+		return 48
+	}
+	return 38
+}
+
+// rgbTo256 maps a color onto the 6×6×6 cube or the grayscale ramp,
+// whichever is closer.
+func rgbTo256(r, g, b uint8) int {
+	cube := func(v uint8) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if absInt(int(v)-int(l)) < absInt(int(v)-int(cubeLevels[best])) {
+				best = i
+			}
+		}
+		return best
+	}
+	ci, cj, ck := cube(r), cube(g), cube(b)
+	cubeIdx := 16 + 36*ci + 6*cj + ck
+	cubeDist := colorDistance(r, g, b, cubeLevels[ci], cubeLevels[cj], cubeLevels[ck])
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	grayStep := minInt(23, maxInt(0, (avg-3)/10))
+	gv := uint8(8 + grayStep*10)
+	if colorDistance(r, g, b, gv, gv, gv) < cubeDist {
+		return 232 + grayStep
+	}
+	return cubeIdx
+}
+
+func indexedToBasic(n int) int {
+	if n < 16 {
+		return n
+	}
+	var r, g, b uint8
+	if n >= 232 {
+		v := uint8(8 + (n-232)*10)
+		r, g, b = v, v, v
+	} else {
+		n -= 16
+		r, g, b = cubeLevels[n/36], cubeLevels[(n/6)%6], cubeLevels[n%6]
+	}
+	return nearestBasic(r, g, b)
+}
+
+func nearestBasic(r, g, b uint8) int {
+	best, bestDist := 0, -1
+	for i, p := range basicPalette {
+		if d := colorDistance(r, g, b, p[0], p[1], p[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func colorDistance(r1, g1, b1, r2, g2, b2 uint8) int {
+	dr, dg, db := int(r1)-int(r2), int(g1)-int(g2), int(b1)-int(b2)
+	return dr*dr + dg*dg + db*db
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func parseColorDepth(value string) (ColorDepth, error) {
+	switch strings.ToLower(value) {
+	case "auto":
+		return ColorDepthAuto, nil
+	case "16", "8", "ansi":
+		return ColorDepth16, nil
+	case "256":
+		return ColorDepth256, nil
+	case "truecolor", "24bit", "true", "24":
+		return ColorDepthTrue, nil
+	}
+	return ColorDepthAuto, fmt.Errorf("invalid argument %q for --color-depth (valid: auto, 16, 256, truecolor)", value)
+}
+
+// detectColorDepth guesses the palette from COLORTERM and TERM. Windows
+// Terminal and the Windows 10+ console both render 24-bit color.
+func detectColorDepth() ColorDepth {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorDepthTrue
+	}
+	if os.Getenv("WT_SESSION") != "" || runtime.GOOS == "windows" {
+		return ColorDepthTrue
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case strings.Contains(term, "direct") || strings.Contains(term, "truecolor"):
+		return ColorDepthTrue
+	case strings.Contains(term, "256color"):
+		return ColorDepth256
+	}
+	return ColorDepth16
+}
+
+// ─────────────────────────────────────────────
+// Themes
+// ─────────────────────────────────────────────
+
+// themeFileTypeKeys names each built-in FileType in theme and config files;
+// categories from the config file may be styled by name as well.
+var themeFileTypeKeys = map[string]FileType{
+	"directory":  FileTypeDirectory,
+	"executable": FileTypeExecutable,
+	"symlink":    FileTypeSymbolicLink,
+	"archive":    FileTypeArchive,
+	"media":      FileTypeMedia,
+	"backup":     FileTypeBackup,
+	"other":      FileTypeOther,
+}
+
+// themeModeKeys names the permission letters colored by colorizeModeString.
+var themeModeKeys = map[string]rune{
+	"directory": 'd',
+	"read":      'r',
+	"write":     'w',
+	"execute":   'x',
+}
+
+var (
+	// modeColors and the table styles are filled in by applyTheme; the
+	// values below match the built-in "dark" theme.
+	modeColors = map[rune]string{
+		'd': "\033[94m",
+		'r': "\033[93m",
+		'w': "\033[91m",
+		'x': "\033[32m",
+	}
+	tableBorderColor  = ""
+	tableHeaderColor  = "\033[32m"
+	tableWarningColor = "\033[1;91m"
+)
+
+// palette is the color state a theme or LS_COLORS sets up; each Lister
+// keeps its own and installs it while active.
+type palette struct {
+	files           map[FileType]string
+	mode            map[rune]string
+	tableBorder     string
+	tableHeader     string
+	tableWarning    string
+	treeGuides      []string
+	lsColorTypes    map[string]string
+	lsColorSuffixes []lsColorSuffix
+}
+
+// defaultPalette is the state before any theme or LS_COLORS is applied.
+var defaultPalette = savePalette()
+
+func savePalette() palette {
+	return palette{
+		files:           maps.Clone(colorMap),
+		mode:            maps.Clone(modeColors),
+		tableBorder:     tableBorderColor,
+		tableHeader:     tableHeaderColor,
+		tableWarning:    tableWarningColor,
+		treeGuides:      slices.Clone(treeDepthColors),
+		lsColorTypes:    maps.Clone(lsColorTypes),
+		lsColorSuffixes: slices.Clone(lsColorSuffixes),
+	}
+}
+
+// install copies p into the package state, which themes then modify.
+func (p palette) install() {
+	colorMap = maps.Clone(p.files)
+	modeColors = maps.Clone(p.mode)
+	tableBorderColor = p.tableBorder
+	tableHeaderColor = p.tableHeader
+	tableWarningColor = p.tableWarning
+	treeDepthColors = slices.Clone(p.treeGuides)
+	lsColorTypes = maps.Clone(p.lsColorTypes)
+	lsColorSuffixes = slices.Clone(p.lsColorSuffixes)
+}
+
+// builtinThemes are written in the same format users put in theme files.
+var builtinThemes = map[string]string{
+	"dark": `
+[files]
+directory  = "bright-blue"
+executable = "green"
+symlink    = "bright-cyan"
+archive    = "bright-red"
+media      = "bright-magenta"
+backup     = "bright-black"
+other      = ""
+
+[tree]
+guides = ["dim yellow", "dim cyan", "dim green", "dim magenta", "dim blue", "dim bright-red"]
+
+[table]
+border  = ""
+header  = "green"
+warning = "bold bright-red"
+
+[mode]
+directory = "bright-blue"
+read      = "bright-yellow"
+write     = "bright-red"
+execute   = "green"
+`,
+	"light": `
+[files]
+directory  = "bold blue"
+executable = "bold green"
+symlink    = "cyan"
+archive    = "red"
+media      = "magenta"
+backup     = "244"
+other      = ""
+
+[tree]
+guides = ["#b58900", "#2aa198", "#859900", "#d33682", "#268bd2", "#cb4b16"]
+
+[table]
+border  = "246"
+header  = "bold blue"
+warning = "bold red"
+
+[mode]
+directory = "blue"
+read      = "#875f00"
+write     = "red"
+execute   = "green"
+`,
+	"solarized": `
+[files]
+directory  = "bold #268bd2"
+executable = "#859900"
+symlink    = "#2aa198"
+archive    = "#dc322f"
+media      = "#d33682"
+backup     = "#586e75"
+other      = ""
+
+[tree]
+guides = ["#b58900", "#2aa198", "#859900", "#d33682", "#268bd2", "#cb4b16"]
+
+[table]
+border  = "#586e75"
+header  = "bold #6c71c4"
+warning = "bold #dc322f"
+
+[mode]
+directory = "#268bd2"
+read      = "#b58900"
+write     = "#dc322f"
+execute   = "#859900"
+`,
+}
+
+// loadTheme resolves name as a built-in preset, a theme file path, or a
+// theme file in the themes directory next to the config file.
+func loadTheme(name string) (*confDoc, error) {
+	if src, ok := builtinThemes[strings.ToLower(name)]; ok {
+		return parseTOMLDoc(src)
+	}
+	if _, err := os.Stat(name); err == nil {
+		return readConfDoc(filepath.Clean(name))
+	}
+	if dir, err := configDir(); err == nil {
+		for _, ext := range []string{".toml", ".yaml", ".yml"} {
+			p := filepath.Join(dir, "themes", name+ext)
+			if _, err := os.Stat(p); err == nil {
+				return readConfDoc(p)
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown theme %q (built-in: dark, light, solarized)", name)
+}
+
+// applyTheme overwrites the color tables with the styles in doc. Keys the
+// theme leaves out keep their current values.
+func applyTheme(doc *confDoc) error {
+	// Reject typos up front, as loadConfig does, rather than silently
+	// ignoring them.
+	for _, e := range doc.Entries {
+		section, key, _ := strings.Cut(e.Key, ".")
+		known := false
+		switch section {
+		case "files":
+			_, known = lookupFileTypeName(key)
+		case "mode":
+			_, known = themeModeKeys[key]
+		case "table":
+			known = key == "border" || key == "header" || key == "warning"
+		case "tree":
+			known = key == "guides"
+		}
+		if !known {
+			return fmt.Errorf("%s: unknown setting (line %d)", e.Key, e.Line)
+		}
+	}
+
+	style := func(key string) (string, bool, error) {
+		spec, ok := doc.Get(key)
+		if !ok {
+			return "", false, nil
+		}
+		st, err := parseStyle(spec)
+		if err != nil {
+			return "", false, fmt.Errorf("%s: %w", key, err)
+		}
+		return st.Seq(), true, nil
+	}
+
+	for _, e := range doc.Section("files") {
+		ft, _ := lookupFileTypeName(e.Key)
+		seq, _, err := style("files." + e.Key)
+		if err != nil {
+			return err
+		}
+		colorMap[ft] = seq
+	}
+	for key, r := range themeModeKeys {
+		seq, ok, err := style("mode." + key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			modeColors[r] = seq
+		}
+	}
+	if seq, ok, err := style("table.border"); err != nil {
+		return err
+	} else if ok {
+		tableBorderColor = seq
+	}
+	if seq, ok, err := style("table.header"); err != nil {
+		return err
+	} else if ok {
+		tableHeaderColor = seq
+	}
+	if seq, ok, err := style("table.warning"); err != nil {
+		return err
+	} else if ok {
+		tableWarningColor = seq
+	}
+
+	if guides, ok := doc.List("tree.guides"); ok && len(guides) > 0 {
+		colors := make([]string, len(guides))
+		for i, spec := range guides {
+			st, err := parseStyle(spec)
+			if err != nil {
+				return fmt.Errorf("tree.guides: %w", err)
+			}
+			colors[i] = st.Seq()
+		}
+		treeDepthColors = colors
+	}
+	return nil
+}
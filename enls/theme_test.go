@@ -0,0 +1,123 @@
+package enls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		spec  string
+		depth ColorDepth
+		want  string // escape sequence, or "error"
+	}{
+		{"", ColorDepth16, ""},
+		{"bold blue", ColorDepth16, "\033[1;34m"},
+		{"bright-red", ColorDepth16, "\033[91m"},
+		{"dim gray", ColorDepth16, "\033[2;90m"},
+		{"black on bright-white", ColorDepth16, "\033[30;107m"},
+		{"208", ColorDepth256, "\033[38;5;208m"},
+		{"#268bd2", ColorDepthTrue, "\033[38;2;38;139;210m"},
+		{"#fff", ColorDepthTrue, "\033[38;2;255;255;255m"},
+		{"#ff0000", ColorDepth16, "\033[91m"},
+		{"underline #ff0000", ColorDepth256, "\033[4;38;5;196m"},
+		{"purple", ColorDepth16, "error"},
+		{"#12345", ColorDepthTrue, "error"},
+		{"256", ColorDepth256, "error"},
+	}
+	saved := colorDepth
+	t.Cleanup(func() { colorDepth = saved })
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			colorDepth = tt.depth
+			st, err := parseStyle(tt.spec)
+			if tt.want == "error" {
+				if err == nil {
+					t.Errorf("parsed as %q, want an error", st.Seq())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := st.Seq(); got != tt.want {
+				t.Errorf("%q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	tests := []struct {
+		name  string
+		toml  string
+		err   string // substring of the error, "" for none
+		check func() bool
+	}{
+		{
+			name: "files and table",
+			toml: "[files]\ndirectory = \"bold blue\"\n[table]\nheader = \"red\"\n",
+			check: func() bool {
+				return colorMap[FileTypeDirectory] == "\033[1;34m" && tableHeaderColor == "\033[31m" &&
+					colorMap[FileTypeExecutable] == defaultPalette.files[FileTypeExecutable]
+			},
+		},
+		{
+			name:  "tree guides",
+			toml:  "[tree]\nguides = [\"red\", \"green\"]\n",
+			check: func() bool { return len(treeDepthColors) == 2 && treeDepthColors[1] == "\033[32m" },
+		},
+		{
+			name:  "mode",
+			toml:  "mode.write = \"yellow\"\n",
+			check: func() bool { return modeColors['w'] == "\033[33m" && modeColors['r'] == defaultPalette.mode['r'] },
+		},
+		{name: "unknown key", toml: "[files]\ndirectory = \"blue\"\nfolder = \"red\"\n", err: "files.folder: unknown setting (line 3)"},
+		{name: "unknown section", toml: "[colors]\ndirectory = \"blue\"\n", err: "colors.directory: unknown setting"},
+		{name: "unknown table key", toml: "[table]\nfooter = \"red\"\n", err: "table.footer: unknown setting"},
+		{name: "bad color", toml: "[files]\nmedia = \"purple\"\n", err: "files.media"},
+		{name: "bad guide", toml: "[tree]\nguides = [\"red\", \"nope\"]\n", err: "tree.guides"},
+	}
+	saved := colorDepth
+	t.Cleanup(func() { colorDepth = saved })
+	colorDepth = ColorDepth16
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keepPalette(t)
+			defaultPalette.install()
+			doc, err := parseTOMLDoc(tt.toml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = applyTheme(doc)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check() {
+				t.Error("theme not applied as expected")
+			}
+		})
+	}
+}
+
+// Every built-in theme must load cleanly.
+func TestBuiltinThemes(t *testing.T) {
+	for name := range builtinThemes {
+		t.Run(name, func(t *testing.T) {
+			keepPalette(t)
+			doc, err := loadTheme(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := applyTheme(doc); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
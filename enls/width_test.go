@@ -0,0 +1,47 @@
+package enls
+
+import "testing"
+
+func TestStringDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"中文", 4},
+		{"한국어", 6},
+		{"e\u0301", 1},                    // e + combining acute
+		{"\u1100\u1161\u11a8", 2},         // conjoining jamo syllable
+		{"\U0001F1E8\U0001F1F3", 2},       // flag
+		{"\U0001F469\u200d\U0001F4BB", 2}, // ZWJ sequence
+		{"\U0001F44D\U0001F3FD", 2},       // skin tone modifier
+		{"\u2764\ufe0f", 2},               // VS16 emoji presentation
+		{"1\ufe0f\u20e3", 2},              // keycap one
+		{"#\u20e3", 2},                    // keycap without VS16
+		{"a1\ufe0f\u20e3b", 4},
+	}
+	for _, tt := range tests {
+		if got := getStringDisplayWidth(tt.s); got != tt.want {
+			t.Errorf("getStringDisplayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateByWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"abcdef", 3, "abc"},
+		{"中文字", 5, "中文"},
+		{"a1\ufe0f\u20e3b", 2, "a"},
+		{"a1\ufe0f\u20e3b", 3, "a1\ufe0f\u20e3"},
+	}
+	for _, tt := range tests {
+		if got := truncateByWidth(tt.s, tt.max); got != tt.want {
+			t.Errorf("truncateByWidth(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package enls
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ─────────────────────────────────────────────
+// Extended attributes, ACLs and capabilities
+// ─────────────────────────────────────────────
+
+const (
+	xattrACLAccess  = "system.posix_acl_access"
+	xattrACLDefault = "system.posix_acl_default"
+	xattrCapability = "security.capability"
+	xattrSELinux    = "security.selinux"
+)
+
+// xattr is one extended attribute of a file.
+type xattr struct {
+	name  string
+	value []byte
+}
+
+// xattrCache holds the attribute names of each path, empty lists
+// included; values are read only when shown (--xattr, caps, -Z).
+var (
+	xattrMu    sync.Mutex
+	xattrCache = map[string][]string{}
+)
+
+// xattrNames lists the extended attribute names of path, sorted.
+func xattrNames(path string) []string {
+	if !onDisk() {
+		return nil
+	}
+	xattrMu.Lock()
+	names, ok := xattrCache[path]
+	xattrMu.Unlock()
+	if ok {
+		return names
+	}
+
+	names, _ = listXattrs(path)
+	sort.Strings(names)
+	xattrMu.Lock()
+	xattrCache[path] = names
+	xattrMu.Unlock()
+	return names
+}
+
+// xattrValue reads one extended attribute of path, if it is set.
+func xattrValue(path, name string) ([]byte, bool) {
+	for _, n := range xattrNames(path) {
+		if n == name {
+			value, err := getXattr(path, name)
+			return value, err == nil
+		}
+	}
+	return nil, false
+}
+
+// fileXattrs reads every extended attribute of path with its value.
+func fileXattrs(path string) []xattr {
+	var xs []xattr
+	for _, name := range xattrNames(path) {
+		value, err := getXattr(path, name)
+		if err != nil {
+			continue
+		}
+		xs = append(xs, xattr{name, value})
+	}
+	return xs
+}
+
+// modeMarkers returns the suffix for the mode string: "+" when a POSIX ACL
+// is set and "@" when other extended attributes exist. SELinux labels are
+// left to -Z.
+func modeMarkers(path string) string {
+	var acl, other bool
+	for _, name := range xattrNames(path) {
+		switch name {
+		case xattrACLAccess, xattrACLDefault:
+			acl = true
+		case xattrSELinux:
+		default:
+			other = true
+		}
+	}
+	markers := ""
+	if acl {
+		markers += "+"
+	}
+	if other {
+		markers += "@"
+	}
+	return markers
+}
+
+// formatXattr renders one attribute for --xattr. ACLs and capabilities are
+// decoded; other values are shown as text when printable and as hex
+// otherwise.
+func formatXattr(x xattr) string {
+	switch x.name {
+	case xattrACLAccess, xattrACLDefault:
+		if s := decodeACL(x.value); s != "" {
+			return x.name + ": " + s
+		}
+	case xattrCapability:
+		if s := decodeCapability(x.value); s != "" {
+			return x.name + ": " + s
+		}
+	}
+	v := strings.TrimSuffix(string(x.value), "\x00")
+	if isPrintable(v) {
+		return x.name + ": " + strconv.Quote(v)
+	}
+	const maxHex = 32
+	if len(x.value) > maxHex {
+		return x.name + ": 0x" + hex.EncodeToString(x.value[:maxHex]) + "..."
+	}
+	return x.name + ": 0x" + hex.EncodeToString(x.value)
+}
+
+func isPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !strconv.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// xattrLines returns the --xattr lines shown under an entry.
+func xattrLines(path string) []string {
+	var lines []string
+	for _, x := range fileXattrs(path) {
+		lines = append(lines, formatXattr(x))
+	}
+	return lines
+}
+
+// decodeACL renders a Linux POSIX ACL xattr in getfacl's short form, e.g.
+// "u::rw-,u:1000:r--,g::r--,m::r--,o::---".
+func decodeACL(b []byte) string {
+	if len(b) < 4 || binary.LittleEndian.Uint32(b) != 2 {
+		return ""
+	}
+	var parts []string
+	for b = b[4:]; len(b) >= 8; b = b[8:] {
+		tag := binary.LittleEndian.Uint16(b[0:2])
+		perm := binary.LittleEndian.Uint16(b[2:4])
+		id := binary.LittleEndian.Uint32(b[4:8])
+		var prefix string
+		switch tag {
+		case 0x01:
+			prefix = "u::"
+		case 0x02:
+			prefix = "u:" + strconv.FormatUint(uint64(id), 10) + ":"
+		case 0x04:
+			prefix = "g::"
+		case 0x08:
+			prefix = "g:" + strconv.FormatUint(uint64(id), 10) + ":"
+		case 0x10:
+			prefix = "m::"
+		case 0x20:
+			prefix = "o::"
+		default:
+			return ""
+		}
+		rwx := []byte("---")
+		if perm&4 != 0 {
+			rwx[0] = 'r'
+		}
+		if perm&2 != 0 {
+			rwx[1] = 'w'
+		}
+		if perm&1 != 0 {
+			rwx[2] = 'x'
+		}
+		parts = append(parts, prefix+string(rwx))
+	}
+	return strings.Join(parts, ",")
+}
+
+// capNames are the Linux capability names by bit number.
+var capNames = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill",
+	"setgid", "setuid", "setpcap", "linux_immutable", "net_bind_service",
+	"net_broadcast", "net_admin", "net_raw", "ipc_lock", "ipc_owner",
+	"sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct",
+	"sys_admin", "sys_boot", "sys_nice", "sys_resource", "sys_time",
+	"sys_tty_config", "mknod", "lease", "audit_write", "audit_control",
+	"setfcap", "mac_override", "mac_admin", "syslog", "wake_alarm",
+	"block_suspend", "audit_read", "perfmon", "bpf", "checkpoint_restore",
+}
+
+func capName(bit int) string {
+	if bit < len(capNames) {
+		return "cap_" + capNames[bit]
+	}
+	return "cap_" + strconv.Itoa(bit)
+}
+
+// decodeCapability renders a security.capability xattr the way getcap
+// does, e.g. "cap_net_bind_service,cap_net_raw=ep".
+func decodeCapability(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	magic := binary.LittleEndian.Uint32(b)
+	effective := magic&0x000001 != 0
+	words := 0
+	switch magic & 0xff000000 {
+	case 0x01000000:
+		words = 1
+	case 0x02000000, 0x03000000: // v3 adds a namespace root uid
+		words = 2
+	default:
+		return ""
+	}
+	if len(b) < 4+8*words {
+		return ""
+	}
+	var permitted, inheritable uint64
+	for i := 0; i < words; i++ {
+		off := 4 + 8*i
+		permitted |= uint64(binary.LittleEndian.Uint32(b[off:])) << (32 * i)
+		inheritable |= uint64(binary.LittleEndian.Uint32(b[off+4:])) << (32 * i)
+	}
+
+	// Group capabilities sharing the same flags: "a,b=ep c=i".
+	var order []string
+	groups := map[string][]string{}
+	for bit := 0; bit < 64; bit++ {
+		p, i := permitted&(1<<bit) != 0, inheritable&(1<<bit) != 0
+		if !p && !i {
+			continue
+		}
+		flags := ""
+		if effective && p {
+			flags += "e"
+		}
+		if i {
+			flags += "i"
+		}
+		if p {
+			flags += "p"
+		}
+		if _, ok := groups[flags]; !ok {
+			order = append(order, flags)
+		}
+		groups[flags] = append(groups[flags], capName(bit))
+	}
+	var parts []string
+	for _, flags := range order {
+		parts = append(parts, strings.Join(groups[flags], ",")+"="+flags)
+	}
+	return strings.Join(parts, " ")
+}
+
+// selinuxContext returns the SELinux label of path, or "?" when there is
+// none, as ls -Z prints.
+func selinuxContext(path string) string {
+	if value, ok := xattrValue(path, xattrSELinux); ok {
+		if ctx := strings.TrimRight(string(value), "\x00"); ctx != "" {
+			return ctx
+		}
+	}
+	return "?"
+}
+
+// fileCapabilities returns the decoded capabilities of path, if any.
+func fileCapabilities(path string) string {
+	if value, ok := xattrValue(path, xattrCapability); ok {
+		return decodeCapability(value)
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package enls
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// listXattrs returns the extended attribute names of path without
+// following symbolic links. Filesystems without xattr support yield none.
+func listXattrs(path string) ([]string, error) {
+	buf, err := xattrRead(func(dest []byte) (int, error) { return unix.Llistxattr(path, dest) })
+	if err != nil || len(buf) == 0 {
+		return nil, err
+	}
+	var names []string
+	for _, name := range bytes.Split(bytes.TrimSuffix(buf, []byte{0}), []byte{0}) {
+		names = append(names, string(name))
+	}
+	return names, nil
+}
+
+// getXattr returns the value of one extended attribute of path.
+func getXattr(path, name string) ([]byte, error) {
+	return xattrRead(func(dest []byte) (int, error) { return unix.Lgetxattr(path, name, dest) })
+}
+
+// xattrRead asks for the size, then reads into a buffer of that size.
+func xattrRead(call func(dest []byte) (int, error)) ([]byte, error) {
+	size, err := call(nil)
+	if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.ENODATA) {
+		return nil, nil
+	}
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	n, err := call(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
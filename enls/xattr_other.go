@@ -0,0 +1,9 @@
+//go:build !linux
+
+package enls
+
+// Extended attributes are only read on Linux.
+
+func listXattrs(path string) ([]string, error) { return nil, nil }
+
+func getXattr(path, name string) ([]byte, error) { return nil, nil }
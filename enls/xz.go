@@ -0,0 +1,663 @@
+package enls
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+)
+
+// ─────────────────────────────────────────────
+// xz decompression (tar.xz archives)
+// ─────────────────────────────────────────────
+//
+// The standard library has no xz reader, so this is a small one: the .xz
+// container around LZMA2, the filter xz(1) uses by default. Branch
+// converters (BCJ) and delta filters are rejected; tarballs do not use
+// them. Only the first stream of a file is read.
+
+var (
+	errXZFormat      = errors.New("xz: corrupt data")
+	errXZUnsupported = errors.New("xz: unsupported filter or dictionary size")
+	errXZCheck       = errors.New("xz: checksum mismatch")
+)
+
+var xzMagic = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+
+// xzMaxDict bounds the dictionary; xz -9 uses 64 MiB.
+const xzMaxDict = 1 << 28
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// xzReader decodes an .xz stream block by block.
+type xzReader struct {
+	r     *bufio.Reader
+	check byte
+	err   error
+
+	// The block being decoded.
+	inBlock    bool
+	blockStart int64 // compressed bytes read when its header started
+	read       int64 // compressed bytes read so far
+	sum        hash.Hash
+	lz         *lzma2Decoder
+	out        []byte
+}
+
+func newXZReader(r io.Reader) (*xzReader, error) {
+	z := &xzReader{r: bufio.NewReader(r)}
+	var hdr [12]byte
+	if _, err := z.readFull(hdr[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hdr[:6], xzMagic) || hdr[6] != 0 || hdr[7] > 0x0f ||
+		crc32.ChecksumIEEE(hdr[6:8]) != binary.LittleEndian.Uint32(hdr[8:]) {
+		return nil, errXZFormat
+	}
+	z.check = hdr[7]
+	return z, nil
+}
+
+func (z *xzReader) readFull(b []byte) (int, error) {
+	n, err := io.ReadFull(z.r, b)
+	z.read += int64(n)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errXZFormat
+	}
+	return n, err
+}
+
+func (z *xzReader) readByte() (byte, error) {
+	var b [1]byte
+	_, err := z.readFull(b[:])
+	return b[0], err
+}
+
+func (z *xzReader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.fill()
+	}
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
+
+// fill decodes the next LZMA2 chunk into z.out, starting and finishing
+// blocks as needed. It returns io.EOF at the stream index.
+func (z *xzReader) fill() error {
+	if !z.inBlock {
+		if err := z.startBlock(); err != nil {
+			return err
+		}
+	}
+	out, done, err := z.lz.chunk(z)
+	if err != nil {
+		return err
+	}
+	if z.sum != nil {
+		z.sum.Write(out)
+	}
+	z.out = out
+	if done {
+		return z.endBlock()
+	}
+	return nil
+}
+
+func (z *xzReader) startBlock() error {
+	z.blockStart = z.read
+	size, err := z.readByte()
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		// The index follows the last block.
+		return io.EOF
+	}
+	hdr := make([]byte, (int(size)+1)*4)
+	hdr[0] = size
+	if _, err := z.readFull(hdr[1:]); err != nil {
+		return err
+	}
+	body, sum := hdr[:len(hdr)-4], hdr[len(hdr)-4:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(sum) {
+		return errXZFormat
+	}
+
+	flags := body[1]
+	if flags&0x3c != 0 {
+		return errXZFormat
+	}
+	rest := body[2:]
+	for _, present := range []bool{flags&0x40 != 0, flags&0x80 != 0} {
+		if present {
+			if _, rest, err = xzVarint(rest); err != nil {
+				return err
+			}
+		}
+	}
+	// Exactly one filter, LZMA2 (id 0x21) with its dictionary byte.
+	if flags&0x03 != 0 {
+		return errXZUnsupported
+	}
+	id, rest, err := xzVarint(rest)
+	if err != nil {
+		return err
+	}
+	propsLen, rest, err := xzVarint(rest)
+	if err != nil {
+		return err
+	}
+	if id != 0x21 || propsLen != 1 || len(rest) < 1 {
+		return errXZUnsupported
+	}
+	dict, err := lzma2DictSize(rest[0])
+	if err != nil {
+		return err
+	}
+
+	z.lz = newLZMA2Decoder(dict)
+	z.sum = xzCheckHash(z.check)
+	z.inBlock = true
+	return nil
+}
+
+// endBlock skips the block padding and verifies the check field.
+func (z *xzReader) endBlock() error {
+	z.inBlock = false
+	for (z.read-z.blockStart)%4 != 0 {
+		if b, err := z.readByte(); err != nil {
+			return err
+		} else if b != 0 {
+			return errXZFormat
+		}
+	}
+	want := make([]byte, xzCheckSize(z.check))
+	if _, err := z.readFull(want); err != nil {
+		return err
+	}
+	if z.sum != nil {
+		got := z.sum.Sum(nil)
+		if z.check == 0x01 || z.check == 0x04 {
+			// CRC32 and CRC64 are stored little-endian.
+			for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+				got[i], got[j] = got[j], got[i]
+			}
+		}
+		if !bytes.Equal(got, want) {
+			return errXZCheck
+		}
+	}
+	return nil
+}
+
+// xzCheckHash returns the hash for a check type, or nil for types that
+// are skipped unverified.
+func xzCheckHash(check byte) hash.Hash {
+	switch check {
+	case 0x01:
+		return crc32.NewIEEE()
+	case 0x04:
+		return crc64.New(crc64Table)
+	case 0x0a:
+		return sha256.New()
+	}
+	return nil
+}
+
+func xzCheckSize(check byte) int {
+	if check == 0 {
+		return 0
+	}
+	return 4 << ((check - 1) / 3)
+}
+
+func xzVarint(b []byte) (uint64, []byte, error) {
+	var v uint64
+	for i := 0; i < len(b) && i < 9; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return v, b[i+1:], nil
+		}
+	}
+	return 0, nil, errXZFormat
+}
+
+func lzma2DictSize(p byte) (int, error) {
+	if p > 40 {
+		return 0, errXZFormat
+	}
+	if p == 40 {
+		return 0, errXZUnsupported
+	}
+	size := (2 | int(p&1)) << (p/2 + 11)
+	if size > xzMaxDict {
+		return 0, errXZUnsupported
+	}
+	return size, nil
+}
+
+// ─────────────────────────────────────────────
+// LZMA2
+// ─────────────────────────────────────────────
+
+// lzWindow is the sliding dictionary. Every byte put into it is also
+// appended to out, the output of the current chunk.
+type lzWindow struct {
+	buf []byte
+	pos int
+	n   int64 // bytes since the last dictionary reset
+	out []byte
+}
+
+func (w *lzWindow) reset() {
+	w.pos = 0
+	w.n = 0
+}
+
+func (w *lzWindow) put(b byte) {
+	w.buf[w.pos] = b
+	if w.pos++; w.pos == len(w.buf) {
+		w.pos = 0
+	}
+	w.n++
+	w.out = append(w.out, b)
+}
+
+// back returns the byte dist positions behind the write position.
+func (w *lzWindow) back(dist int) byte {
+	i := w.pos - dist
+	if i < 0 {
+		i += len(w.buf)
+	}
+	return w.buf[i]
+}
+
+func (w *lzWindow) has(dist int) bool {
+	return dist >= 1 && dist <= len(w.buf) && int64(dist) <= w.n
+}
+
+// rangeDecoder reads one LZMA chunk's range-coded input.
+type rangeDecoder struct {
+	in   []byte
+	pos  int
+	rng  uint32
+	code uint32
+	bad  bool
+}
+
+func (rc *rangeDecoder) init(in []byte) {
+	*rc = rangeDecoder{in: in, rng: 0xffffffff}
+	if len(in) < 5 || in[0] != 0 {
+		rc.bad = true
+		return
+	}
+	rc.code = binary.BigEndian.Uint32(in[1:5])
+	rc.pos = 5
+}
+
+func (rc *rangeDecoder) normalize() {
+	if rc.rng < 1<<24 {
+		rc.rng <<= 8
+		if rc.pos < len(rc.in) {
+			rc.code = rc.code<<8 | uint32(rc.in[rc.pos])
+			rc.pos++
+		} else {
+			rc.code <<= 8
+			rc.bad = true
+		}
+	}
+}
+
+const lzmaProbInit = 1 << 10
+
+func (rc *rangeDecoder) bit(p *uint16) int {
+	rc.normalize()
+	bound := (rc.rng >> 11) * uint32(*p)
+	if rc.code < bound {
+		rc.rng = bound
+		*p += (1<<11 - *p) >> 5
+		return 0
+	}
+	rc.rng -= bound
+	rc.code -= bound
+	*p -= *p >> 5
+	return 1
+}
+
+// tree decodes an n-bit symbol MSB first; probs has 1<<n entries.
+func (rc *rangeDecoder) tree(probs []uint16, n int) int {
+	m := 1
+	for i := 0; i < n; i++ {
+		m = m<<1 | rc.bit(&probs[m])
+	}
+	return m - 1<<n
+}
+
+// reverseTree decodes an n-bit symbol LSB first. Tree node m >= 1 uses
+// probs[m-1].
+func (rc *rangeDecoder) reverseTree(probs []uint16, n int) int {
+	m, sym := 1, 0
+	for i := 0; i < n; i++ {
+		b := rc.bit(&probs[m-1])
+		m = m<<1 | b
+		sym |= b << i
+	}
+	return sym
+}
+
+func (rc *rangeDecoder) direct(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		rc.normalize()
+		rc.rng >>= 1
+		b := uint32(0)
+		if rc.code >= rc.rng {
+			rc.code -= rc.rng
+			b = 1
+		}
+		v = v<<1 | b
+	}
+	return v
+}
+
+type lzmaLenDecoder struct {
+	choice, choice2 uint16
+	low, mid        [16][8]uint16
+	high            [256]uint16
+}
+
+func (l *lzmaLenDecoder) reset() {
+	l.choice, l.choice2 = lzmaProbInit, lzmaProbInit
+	for i := range l.low {
+		fillProbs(l.low[i][:])
+		fillProbs(l.mid[i][:])
+	}
+	fillProbs(l.high[:])
+}
+
+// decode returns the match length minus two.
+func (l *lzmaLenDecoder) decode(rc *rangeDecoder, posState int) int {
+	if rc.bit(&l.choice) == 0 {
+		return rc.tree(l.low[posState][:], 3)
+	}
+	if rc.bit(&l.choice2) == 0 {
+		return 8 + rc.tree(l.mid[posState][:], 3)
+	}
+	return 16 + rc.tree(l.high[:], 8)
+}
+
+func fillProbs(p []uint16) {
+	for i := range p {
+		p[i] = lzmaProbInit
+	}
+}
+
+// lzmaState holds the adaptive probabilities and match history.
+type lzmaState struct {
+	lc, lp, pb int
+
+	state               int
+	rep                 [4]int
+	isMatch, isRep0Long [12 << 4]uint16
+	isRep, isRepG0      [12]uint16
+	isRepG1, isRepG2    [12]uint16
+	posSlot             [4][64]uint16
+	posSpecial          [114]uint16
+	align               [16]uint16
+	lenDec, repLenDec   lzmaLenDecoder
+	literal             []uint16
+}
+
+func (s *lzmaState) reset() {
+	s.state = 0
+	s.rep = [4]int{}
+	fillProbs(s.isMatch[:])
+	fillProbs(s.isRep0Long[:])
+	fillProbs(s.isRep[:])
+	fillProbs(s.isRepG0[:])
+	fillProbs(s.isRepG1[:])
+	fillProbs(s.isRepG2[:])
+	for i := range s.posSlot {
+		fillProbs(s.posSlot[i][:])
+	}
+	fillProbs(s.posSpecial[:])
+	fillProbs(s.align[:])
+	s.lenDec.reset()
+	s.repLenDec.reset()
+	n := 0x300 << (s.lc + s.lp)
+	if cap(s.literal) < n {
+		s.literal = make([]uint16, n)
+	}
+	s.literal = s.literal[:n]
+	fillProbs(s.literal)
+}
+
+// lzma2Decoder decodes the LZMA2 chunks of one block.
+type lzma2Decoder struct {
+	win       lzWindow
+	st        lzmaState
+	rc        rangeDecoder
+	needDict  bool
+	needProps bool
+	needState bool
+}
+
+func newLZMA2Decoder(dict int) *lzma2Decoder {
+	return &lzma2Decoder{win: lzWindow{buf: make([]byte, dict)}, needDict: true, needProps: true}
+}
+
+// chunk decodes the next chunk, returning its output and whether it was
+// the end-of-block marker.
+func (d *lzma2Decoder) chunk(z *xzReader) ([]byte, bool, error) {
+	d.win.out = d.win.out[:0]
+	ctrl, err := z.readByte()
+	if err != nil {
+		return nil, false, err
+	}
+	if ctrl == 0x00 {
+		return nil, true, nil
+	}
+
+	var hdr [5]byte
+	if ctrl < 0x80 {
+		// Uncompressed chunk; 0x01 also resets the dictionary.
+		if ctrl > 0x02 || ctrl == 0x02 && d.needDict {
+			return nil, false, errXZFormat
+		}
+		if _, err := z.readFull(hdr[:2]); err != nil {
+			return nil, false, err
+		}
+		if ctrl == 0x01 {
+			d.win.reset()
+			d.needDict = false
+		}
+		data := make([]byte, int(binary.BigEndian.Uint16(hdr[:2]))+1)
+		if _, err := z.readFull(data); err != nil {
+			return nil, false, err
+		}
+		for _, b := range data {
+			d.win.put(b)
+		}
+		d.needState = true
+		return d.win.out, false, nil
+	}
+
+	if _, err := z.readFull(hdr[:4]); err != nil {
+		return nil, false, err
+	}
+	unpacked := int(ctrl&0x1f)<<16 + int(binary.BigEndian.Uint16(hdr[:2])) + 1
+	packed := int(binary.BigEndian.Uint16(hdr[2:4])) + 1
+
+	switch reset := (ctrl >> 5) & 0x03; {
+	case reset == 3:
+		d.win.reset()
+		d.needDict = false
+		fallthrough
+	case reset == 2:
+		p, err := z.readByte()
+		if err != nil {
+			return nil, false, err
+		}
+		if p >= 9*5*5 {
+			return nil, false, errXZFormat
+		}
+		d.st.lc, d.st.lp, d.st.pb = int(p%9), int(p/9%5), int(p/45)
+		if d.st.lc+d.st.lp > 4 {
+			return nil, false, errXZFormat
+		}
+		d.needProps = false
+		fallthrough
+	case reset == 1:
+		d.st.reset()
+		d.needState = false
+	}
+	if d.needDict || d.needProps || d.needState {
+		return nil, false, errXZFormat
+	}
+
+	in := make([]byte, packed)
+	if _, err := z.readFull(in); err != nil {
+		return nil, false, err
+	}
+	d.rc.init(in)
+	if err := d.decode(unpacked); err != nil {
+		return nil, false, err
+	}
+	return d.win.out, false, nil
+}
+
+// decode produces n bytes of LZMA output from d.rc.
+func (d *lzma2Decoder) decode(n int) error {
+	s, rc, w := &d.st, &d.rc, &d.win
+	pbMask := 1<<s.pb - 1
+	lpMask := 1<<s.lp - 1
+	for end := w.n + int64(n); w.n < end; {
+		if rc.bad {
+			return errXZFormat
+		}
+		posState := int(w.n) & pbMask
+
+		if rc.bit(&s.isMatch[s.state<<4|posState]) == 0 {
+			prev := 0
+			if w.n > 0 {
+				prev = int(w.back(1))
+			}
+			probs := s.literal[0x300*((int(w.n)&lpMask)<<s.lc+prev>>(8-s.lc)):]
+			sym := 1
+			if s.state >= 7 {
+				if !w.has(s.rep[0] + 1) {
+					return errXZFormat
+				}
+				match := int(w.back(s.rep[0] + 1))
+				for sym < 0x100 {
+					matchBit := match >> 7 & 1
+					match <<= 1
+					b := rc.bit(&probs[(1+matchBit)<<8+sym])
+					sym = sym<<1 | b
+					if b != matchBit {
+						break
+					}
+				}
+			}
+			for sym < 0x100 {
+				sym = sym<<1 | rc.bit(&probs[sym])
+			}
+			w.put(byte(sym))
+			switch {
+			case s.state < 4:
+				s.state = 0
+			case s.state < 10:
+				s.state -= 3
+			default:
+				s.state -= 6
+			}
+			continue
+		}
+
+		var length int
+		if rc.bit(&s.isRep[s.state]) == 1 {
+			if rc.bit(&s.isRepG0[s.state]) == 0 {
+				if rc.bit(&s.isRep0Long[s.state<<4|posState]) == 0 {
+					// Short rep: one byte from rep0.
+					if !w.has(s.rep[0] + 1) {
+						return errXZFormat
+					}
+					if s.state < 7 {
+						s.state = 9
+					} else {
+						s.state = 11
+					}
+					w.put(w.back(s.rep[0] + 1))
+					continue
+				}
+			} else {
+				var dist int
+				if rc.bit(&s.isRepG1[s.state]) == 0 {
+					dist = s.rep[1]
+				} else {
+					if rc.bit(&s.isRepG2[s.state]) == 0 {
+						dist = s.rep[2]
+					} else {
+						dist = s.rep[3]
+						s.rep[3] = s.rep[2]
+					}
+					s.rep[2] = s.rep[1]
+				}
+				s.rep[1] = s.rep[0]
+				s.rep[0] = dist
+			}
+			length = s.repLenDec.decode(rc, posState)
+			if s.state < 7 {
+				s.state = 8
+			} else {
+				s.state = 11
+			}
+		} else {
+			s.rep[3], s.rep[2], s.rep[1] = s.rep[2], s.rep[1], s.rep[0]
+			length = s.lenDec.decode(rc, posState)
+			if s.state < 7 {
+				s.state = 7
+			} else {
+				s.state = 10
+			}
+			s.rep[0] = d.distance(length)
+		}
+
+		dist := s.rep[0] + 1
+		if !w.has(dist) {
+			return errXZFormat
+		}
+		for i := length + 2; i > 0 && w.n < end; i-- {
+			w.put(w.back(dist))
+		}
+	}
+	if rc.bad {
+		return errXZFormat
+	}
+	return nil
+}
+
+// distance decodes a match distance minus one.
+func (d *lzma2Decoder) distance(length int) int {
+	s, rc := &d.st, &d.rc
+	slot := rc.tree(s.posSlot[min(length, 3)][:], 6)
+	if slot < 4 {
+		return slot
+	}
+	bits := slot>>1 - 1
+	dist := (2 | slot&1) << bits
+	if slot < 14 {
+		return dist + rc.reverseTree(s.posSpecial[dist-slot:], bits)
+	}
+	dist += int(rc.direct(bits-4)) << 4
+	return dist + rc.reverseTree(s.align[:], 4)
+}
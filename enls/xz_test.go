@@ -0,0 +1,141 @@
+package enls
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The testdata/xz_*.xz fixtures were written with Python's lzma module.
+// xz_crc64.xz holds xzNoise followed by xzText, so it starts with an
+// uncompressed LZMA2 chunk that the next, compressed chunk matches into;
+// the others hold xzText with different checks and literal settings.
+
+func xzText() []byte {
+	var b bytes.Buffer
+	for i := range 3000 {
+		fmt.Fprintf(&b, "line %d: the quick brown fox\n", i)
+	}
+	return b.Bytes()
+}
+
+// xzNoise is 70000 xorshift64* bytes, which LZMA cannot compress.
+func xzNoise() []byte {
+	b := make([]byte, 70000)
+	x := uint64(0x9E3779B97F4A7C15)
+	for i := range b {
+		x ^= x >> 12
+		x ^= x << 25
+		x ^= x >> 27
+		b[i] = byte((x * 0x2545F4914F6CDD1D) >> 56)
+	}
+	return b
+}
+
+func readXZ(data []byte) ([]byte, error) {
+	z, err := newXZReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(z)
+}
+
+func TestXZReader(t *testing.T) {
+	tests := []struct {
+		file string
+		want []byte
+	}{
+		{"xz_crc64.xz", append(xzNoise(), xzText()...)},
+		{"xz_crc32.xz", xzText()}, // lc=1 lp=2 pb=0
+		{"xz_sha256.xz", xzText()},
+		{"xz_none.xz", xzText()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := readXZ(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decoded %d bytes, want %d matching bytes", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestXZReaderErrors(t *testing.T) {
+	crc32File, err := os.ReadFile(filepath.Join("testdata", "xz_crc32.xz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcj, err := os.ReadFile(filepath.Join("testdata", "xz_bcj.xz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The CRC32 sits just before the 0x00 index indicator; the stream
+	// footer is 12 bytes and a one-record index 12 more.
+	badCheck := bytes.Clone(crc32File)
+	badCheck[len(badCheck)-24-4] ^= 0xff
+	badData := bytes.Clone(crc32File)
+	badData[len(badData)/2] ^= 0x55
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not xz", []byte("plain text, nothing to see"), errXZFormat},
+		{"truncated", crc32File[:len(crc32File)/2], errXZFormat},
+		{"bad check", badCheck, errXZCheck},
+		{"bad data", badData, nil},
+		{"bcj filter", bcj, errXZUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readXZ(tt.data)
+			if err == nil {
+				t.Fatal("decoded without error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTarXZ(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "release.tar.xz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tr, err := tarReader(f, "tar+xz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeReg {
+			names = append(names, h.Name)
+		}
+	}
+	if got := len(names); got != 2 || names[0] != "docs/readme.txt" || names[1] != "bin/tool" {
+		t.Errorf("members %q, want docs/readme.txt and bin/tool", names)
+	}
+}
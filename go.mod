@@ -0,0 +1,8 @@
+module github.com/Geekstrange/enhanced-ls
+
+go 1.26.0
+
+require (
+	golang.org/x/sys v0.48.0
+	golang.org/x/term v0.46.0
+)